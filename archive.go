@@ -0,0 +1,132 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ARCHIVESEPARATOR is separator between archive path and entry path.
+const ARCHIVESEPARATOR = "!/"
+
+// IsArchive is whether path is supported archive (zip, tar, tar.gz, tgz) or not.
+func IsArchive(p string) bool {
+	return archiveKind(p) != ""
+}
+
+func archiveKind(p string) string {
+	l := strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(l, ".zip"):
+		return "zip"
+	case strings.HasSuffix(l, ".tar"):
+		return "tar"
+	case strings.HasSuffix(l, ".tar.gz"), strings.HasSuffix(l, ".tgz"):
+		return "tgz"
+	}
+	return ""
+}
+
+// archiveFileInfo is os.FileInfo for directories not stored in archive.
+type archiveFileInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (fi archiveFileInfo) Name() string       { return fi.name }
+func (fi archiveFileInfo) Size() int64        { return 0 }
+func (fi archiveFileInfo) Mode() os.FileMode  { return os.ModeDir | 0755 }
+func (fi archiveFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi archiveFileInfo) IsDir() bool        { return true }
+func (fi archiveFileInfo) Sys() interface{}   { return nil }
+
+// archiveInfos return Info of all entries in archive p as virtual directory.
+// depth is depth of top level entries.
+func archiveInfos(fsys FileSystem, p string, fi os.FileInfo, depth int) ([]Info, error) {
+	rc, err := fsys.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	entries := map[string]os.FileInfo{}
+	add := func(name string, efi os.FileInfo) {
+		name = path.Clean("/" + strings.Replace(name, "\\", "/", -1))[1:]
+		if name == "" {
+			return
+		}
+		entries[name] = efi
+		// Synthesize parent directories.
+		for d := path.Dir(name); d != "."; d = path.Dir(d) {
+			if _, ok := entries[d]; ok {
+				break
+			}
+			entries[d] = archiveFileInfo{name: path.Base(d), modTime: fi.ModTime()}
+		}
+	}
+
+	switch archiveKind(p) {
+	case "zip":
+		ra, ok := rc.(io.ReaderAt)
+		size := fi.Size()
+		if !ok {
+			b, err := ioutil.ReadAll(rc)
+			if err != nil {
+				return nil, err
+			}
+			ra, size = bytes.NewReader(b), int64(len(b))
+		}
+		zr, err := zip.NewReader(ra, size)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			add(f.Name, f.FileInfo())
+		}
+	case "tar", "tgz":
+		var r io.Reader = rc
+		if archiveKind(p) == "tgz" {
+			gr, err := gzip.NewReader(rc)
+			if err != nil {
+				return nil, err
+			}
+			defer gr.Close()
+			r = gr
+		}
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			add(hdr.Name, hdr.FileInfo())
+		}
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]Info, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, Info{
+			Path:  p + ARCHIVESEPARATOR + name,
+			Fi:    entries[name],
+			Depth: depth + strings.Count(name, "/"),
+		})
+	}
+	return infos, nil
+}
@@ -0,0 +1,165 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func setupArchive(t *testing.T) string {
+	/*
+	 * Create archives under setup() tree.
+	 *
+	 * |-- release.zip
+	 * |	|-- bin/tool
+	 * |	|-- README [3 days old]
+	 * |-- dir2 (dir)
+	 * |	|-- logs.tar.gz
+	 * |	|	|-- a/ (dir)
+	 * |	|	|-- a/b/c
+	 *
+	 */
+	tmp := setup()
+
+	zf, err := os.Create(filepath.Join(tmp, "release.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(zf)
+	w, _ := zw.CreateHeader(&zip.FileHeader{Name: "bin/tool", Method: zip.Deflate, Modified: time.Now()})
+	w.Write([]byte("tool"))
+	fh := &zip.FileHeader{Name: "README", Method: zip.Deflate}
+	fh.Modified = time.Now().Add(-3 * 24 * time.Hour)
+	w, _ = zw.CreateHeader(fh)
+	w.Write([]byte("readme"))
+	zw.Close()
+	zf.Close()
+
+	tf, err := os.Create(filepath.Join(tmp, "dir2", "logs.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(tf)
+	tw := tar.NewWriter(gw)
+	tw.WriteHeader(&tar.Header{Name: "./a/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: time.Now()})
+	tw.WriteHeader(&tar.Header{Name: "./a/b/c", Typeflag: tar.TypeReg, Mode: 0644, Size: 1, ModTime: time.Now()})
+	tw.Write([]byte("c"))
+	tw.Close()
+	gw.Close()
+	tf.Close()
+
+	return tmp
+}
+
+// TestGetInfosArchive is test GetInfos func with archive option.
+func TestGetInfosArchive(t *testing.T) {
+	tmp := setupArchive(t)
+	defer shutdown(tmp)
+
+	// Without Archive option, archives are leaves.
+	exp := 13
+	cnt := getCnt(GetFiles, tmp, Option{Recurse: true}, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+
+	// Archive files + bin/tool, README, a/b/c.
+	exp = 16
+	cnt = getCnt(GetFiles, tmp, Option{Recurse: true, Archive: true}, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+
+	// bin, a, a/b.
+	exp = 10
+	cnt = getCnt(GetDirs, tmp, Option{Recurse: true, Archive: true}, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+
+	tool := filepath.Join(tmp, "release.zip") + ARCHIVESEPARATOR + "bin/tool"
+	i := GetFile(tool, Option{})
+	if i.Err == nil {
+		t.Fatalf("Expected error but actual: [%v]\n", i.Path)
+	}
+	infos, err := GetFiles(tmp, Option{Recurse: true, Archive: true, Matches: []string{`bin/tool$`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []Info
+	for i := range infos {
+		found = append(found, i)
+	}
+	if len(found) != 1 || found[0].Path != tool || found[0].Depth != 3 || found[0].Fi.Size() != 4 {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", tool, found)
+	}
+}
+
+// TestGetInfosArchiveDepth is test GetInfos func with archive and depth option.
+func TestGetInfosArchiveDepth(t *testing.T) {
+	tmp := setupArchive(t)
+	defer shutdown(tmp)
+
+	// Top level archive is not descended without Recurse or Depth.
+	exp := 4
+	cnt := getCnt(GetFiles, tmp, Option{Archive: true}, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+
+	// dir2/logs.tar.gz, release.zip!/README, release.zip!/bin.
+	exp = 12
+	cnt = getCnt(GetInfos, tmp, Option{Archive: true, Depth: 2}, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+
+	// Root is archive.
+	exp = 2
+	cnt = getCnt(GetInfos, filepath.Join(tmp, "dir2", "logs.tar.gz"), Option{Archive: true}, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+}
+
+// TestGetInfosArchiveTime is test GetInfos func with archive and time option.
+func TestGetInfosArchiveTime(t *testing.T) {
+	tmp := setupArchive(t)
+	defer shutdown(tmp)
+
+	opt := Option{
+		Recurse: true,
+		Archive: true,
+		Times: []Time{
+			Time{
+				Base: time.Now().Add(-2 * 24 * time.Hour),
+				Ope:  "lt",
+			},
+		},
+	}
+
+	// dir0/file1 and release.zip!/README.
+	exp := 2
+	cnt := getCnt(GetFiles, tmp, opt, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+}
+
+// TestGetInfosArchiveBroken is test GetInfos func with broken archive.
+func TestGetInfosArchiveBroken(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	broken := filepath.Join(tmp, "broken.zip")
+	os.Create(broken)
+
+	_, errs := collect(GetFiles, tmp, Option{Recurse: true, Archive: true}, t)
+	if len(errs) != 1 || errs[0].Path != broken {
+		t.Fatalf("Expected: [%v] error but actual: [%v]\n", broken, errs)
+	}
+}
@@ -0,0 +1,203 @@
+package file
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// scanCacheVersion is version of cache file format.
+const scanCacheVersion = 1
+
+// scanCacheRacy is age of directory mtime not trusted, since changes within same timestamp are not seen.
+var scanCacheRacy = 2 * time.Second
+
+// cachedDir is direct entries of directory.
+type cachedDir struct {
+	ModTime int64    `json:"t"`
+	Files   int64    `json:"f"`
+	Size    int64    `json:"s"`
+	Dirs    []string `json:"d,omitempty"`
+	// Racy entry is kept only to find its subtree, never used as cache.
+	Racy bool `json:"r,omitempty"`
+}
+
+type scanCacheFile struct {
+	Version int                  `json:"version"`
+	Dirs    map[string]cachedDir `json:"dirs"`
+}
+
+// ScanCache is on-disk cache of directory entries for GetDirInfos, keyed by directory path and mtime.
+// Unchanged directories are not read again, so only modified subtrees are re-read.
+// Directory mtime is not changed when file content is rewritten in place, so such size changes are not seen
+// until the directory is changed or Invalidate is called.
+type ScanCache struct {
+	path string
+
+	mu    sync.Mutex
+	dirs  map[string]cachedDir
+	dirty bool
+}
+
+// OpenScanCache load cache from path. Missing, broken or old version cache is started empty.
+func OpenScanCache(path string) (*ScanCache, error) {
+	c := &ScanCache{path: path, dirs: map[string]cachedDir{}}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// gzip checksum detect broken cache.
+	var cf scanCacheFile
+	zr, err := gzip.NewReader(f)
+	if err == nil {
+		err = json.NewDecoder(zr).Decode(&cf)
+	}
+	if err == nil {
+		_, err = ioutil.ReadAll(zr)
+	}
+	if err != nil || cf.Version != scanCacheVersion || cf.Dirs == nil {
+		c.dirty = true
+		return c, nil
+	}
+	c.dirs = cf.Dirs
+	return c, nil
+}
+
+// Len return number of cached directories.
+func (c *ScanCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, d := range c.dirs {
+		if !d.Racy {
+			n++
+		}
+	}
+	return n
+}
+
+// Save write cache to its path atomically if changed.
+func (c *ScanCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(c.path), "."+filepath.Base(c.path)+".")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	zw := gzip.NewWriter(f)
+	err = json.NewEncoder(zw).Encode(scanCacheFile{Version: scanCacheVersion, Dirs: c.dirs})
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, c.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Invalidate remove path and its subtree from cache.
+func (c *ScanCache) Invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeTree(path)
+}
+
+// removeTree remove path and cached subdirectories of it.
+func (c *ScanCache) removeTree(path string) {
+	d, ok := c.dirs[path]
+	if !ok {
+		return
+	}
+	delete(c.dirs, path)
+	c.dirty = true
+	for _, name := range d.Dirs {
+		c.removeTree(filepath.Join(path, name))
+	}
+}
+
+// get return cached entries of path if mtime is same.
+func (c *ScanCache) get(path string, modTime time.Time) (cachedDir, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.dirs[path]
+	if !ok || d.Racy || d.ModTime != modTime.UnixNano() {
+		return cachedDir{}, false
+	}
+	return d, true
+}
+
+// put store entries of path, and remove subtrees of removed directories.
+func (c *ScanCache) put(path string, d cachedDir, modTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.dirs[path]; ok {
+		exist := make(map[string]bool, len(d.Dirs))
+		for _, name := range d.Dirs {
+			exist[name] = true
+		}
+		for _, name := range old.Dirs {
+			if !exist[name] {
+				c.removeTree(filepath.Join(path, name))
+			}
+		}
+	}
+	if time.Since(modTime) < scanCacheRacy {
+		// Too new to trust.
+		d.Racy = true
+	}
+	c.dirs[path] = d
+	c.dirty = true
+}
+
+// readDirEntries return direct entries of directory p, using cache if not changed.
+func readDirEntries(fsys FileSystem, c *ScanCache, p string, fi os.FileInfo) (cachedDir, error) {
+	if c != nil {
+		if d, ok := c.get(p, fi.ModTime()); ok {
+			return d, nil
+		}
+	}
+	fis, err := fsys.ReadDir(p)
+	if err != nil {
+		return cachedDir{}, err
+	}
+	d := cachedDir{ModTime: fi.ModTime().UnixNano()}
+	for _, fi := range fis {
+		if fi.IsDir() {
+			d.Dirs = append(d.Dirs, fi.Name())
+		} else {
+			d.Files++
+			d.Size += fi.Size()
+		}
+	}
+	if c != nil {
+		c.put(p, d, fi.ModTime())
+	}
+	return d, nil
+}
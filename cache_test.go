@@ -0,0 +1,191 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countFS count ReadDir calls.
+type countFS struct {
+	OsFS
+	readDirs int64
+}
+
+func (c *countFS) ReadDir(name string) ([]os.FileInfo, error) {
+	atomic.AddInt64(&c.readDirs, 1)
+	return c.OsFS.ReadDir(name)
+}
+
+// ageDirs set mtime of all directories under root to old.
+func ageDirs(t *testing.T, root string, old time.Time) {
+	dirs, err := GetDirs(root, Option{Recurse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for d := range dirs {
+		os.Chtimes(d.Path, old, old)
+	}
+}
+
+// scanRoot return DirInfo of root and ReadDir count by GetDirInfos with cache.
+func scanRoot(t *testing.T, root string, c *ScanCache) (DirInfo, int64) {
+	fsys := &countFS{}
+	dis, err := GetDirInfos(root, Option{Recurse: true, FS: fsys, Cache: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res DirInfo
+	for di := range dis {
+		if di.Err != nil {
+			t.Fatal(di.Err)
+		}
+		if di.Path == root {
+			res = di
+		}
+	}
+	return res, atomic.LoadInt64(&fsys.readDirs)
+}
+
+func checkDirInfo(t *testing.T, root string, actual DirInfo) {
+	want := GetDirInfo(root)
+	if actual.DirSize != want.DirSize || actual.DirCount != want.DirCount || actual.FileCount != want.FileCount {
+		t.Fatalf("Expected: [%d %d %d] but actual: [%d %d %d]\n", want.DirSize, want.DirCount, want.FileCount, actual.DirSize, actual.DirCount, actual.FileCount)
+	}
+}
+
+// TestScanCache is test GetDirInfos func with ScanCache.
+func TestScanCache(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+	cacheDir, _ := ioutil.TempDir("", "cache")
+	defer os.RemoveAll(cacheDir)
+	cachePath := filepath.Join(cacheDir, "scan.cache")
+
+	old := time.Now().Add(-time.Hour)
+	ioutil.WriteFile(filepath.Join(tmp, "dir0", "file0"), []byte("file0"), 0644)
+	ageDirs(t, tmp, old)
+
+	c, err := OpenScanCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	di, n := scanRoot(t, tmp, c)
+	checkDirInfo(t, tmp, di)
+	if n != di.DirCount+1 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", di.DirCount+1, n)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged tree is not read.
+	c, err = OpenScanCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	di, n = scanRoot(t, tmp, c)
+	checkDirInfo(t, tmp, di)
+	if n != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 0, n)
+	}
+
+	// Only changed directory is read.
+	ioutil.WriteFile(filepath.Join(tmp, "dir1", "new"), []byte("new"), 0644)
+	os.Chtimes(filepath.Join(tmp, "dir1"), old.Add(time.Minute), old.Add(time.Minute))
+	di, n = scanRoot(t, tmp, c)
+	checkDirInfo(t, tmp, di)
+	if n != 1 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 1, n)
+	}
+
+	// Removed subtree is dropped from cache.
+	cnt := c.Len()
+	os.RemoveAll(filepath.Join(tmp, "dir0", "bar"))
+	os.Chtimes(filepath.Join(tmp, "dir0"), old.Add(time.Minute), old.Add(time.Minute))
+	di, n = scanRoot(t, tmp, c)
+	checkDirInfo(t, tmp, di)
+	if n != 1 || c.Len() != cnt-1 {
+		t.Fatalf("Expected: [%d] [%d] but actual: [%d] [%d]\n", 1, cnt-1, n, c.Len())
+	}
+
+	// Invalidated subtree is read again. dir0, dir0/foo and dir0/hoge.
+	c.Invalidate(filepath.Join(tmp, "dir0"))
+	di, n = scanRoot(t, tmp, c)
+	checkDirInfo(t, tmp, di)
+	if n != 3 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 3, n)
+	}
+}
+
+// TestScanCacheRacy is test directory changed just now is not cached.
+func TestScanCacheRacy(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	ageDirs(t, tmp, time.Now().Add(-time.Hour))
+	os.Chtimes(filepath.Join(tmp, "dir2"), time.Now(), time.Now())
+
+	c, _ := OpenScanCache(filepath.Join(tmp, "unused"))
+	scanRoot(t, tmp, c)
+	if _, n := scanRoot(t, tmp, c); n != 1 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 1, n)
+	}
+
+	// Subtree under racy directory is still invalidated.
+	// dir2, dir0, dir0/bar, dir0/foo and dir0/hoge.
+	os.Chtimes(filepath.Join(tmp, "dir0"), time.Now(), time.Now())
+	scanRoot(t, tmp, c)
+	c.Invalidate(filepath.Join(tmp, "dir0"))
+	if _, n := scanRoot(t, tmp, c); n != 5 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 5, n)
+	}
+}
+
+// TestScanCacheCorrupt is test broken cache is recovered as empty.
+func TestScanCacheCorrupt(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+	cacheDir, _ := ioutil.TempDir("", "cache")
+	defer os.RemoveAll(cacheDir)
+	cachePath := filepath.Join(cacheDir, "scan.cache")
+
+	ageDirs(t, tmp, time.Now().Add(-time.Hour))
+	c, _ := OpenScanCache(cachePath)
+	scanRoot(t, tmp, c)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, broken := range map[string][]byte{
+		"garbage":   []byte("not a cache"),
+		"truncated": b[:len(b)/2],
+		"flipped":   append(append([]byte{}, b[:len(b)-5]...), b[len(b)-5]^0xff, b[len(b)-4], b[len(b)-3], b[len(b)-2], b[len(b)-1]),
+	} {
+		ioutil.WriteFile(cachePath, broken, 0644)
+		c, err := OpenScanCache(cachePath)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if c.Len() != 0 {
+			t.Fatalf("%s: Expected: [%d] but actual: [%d]\n", name, 0, c.Len())
+		}
+		di, _ := scanRoot(t, tmp, c)
+		checkDirInfo(t, tmp, di)
+
+		// Broken cache is rewritten.
+		if err := c.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if c, _ := OpenScanCache(cachePath); c.Len() == 0 {
+			t.Fatalf("%s: Expected: recovered but actual: [%d]\n", name, c.Len())
+		}
+	}
+}
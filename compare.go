@@ -0,0 +1,93 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"os"
+	"time"
+)
+
+// Compare is strategy to decide whether dst is up to date with src.
+// Return true to skip copy. Built-in strategies read src from OS filesystem,
+// use CompareChecksumFS when CopyOption.FS is set.
+type Compare func(src, dst string, sfi, dfi os.FileInfo) (bool, error)
+
+// CompareAlways never skip, always copy.
+func CompareAlways(src, dst string, sfi, dfi os.FileInfo) (bool, error) {
+	return false, nil
+}
+
+// CompareNever always skip, never overwrite existing dst.
+func CompareNever(src, dst string, sfi, dfi os.FileInfo) (bool, error) {
+	return true, nil
+}
+
+// CompareSizeTime skip if size is same and mod time differ within tolerance.
+// Use 2 * time.Second for FAT and SMB shares.
+func CompareSizeTime(tolerance time.Duration) Compare {
+	return func(src, dst string, sfi, dfi os.FileInfo) (bool, error) {
+		if sfi.Size() != dfi.Size() {
+			return false, nil
+		}
+		return withinTolerance(sfi.ModTime().Sub(dfi.ModTime()), tolerance), nil
+	}
+}
+
+// CompareNewer skip unless src is newer than dst over tolerance.
+func CompareNewer(tolerance time.Duration) Compare {
+	return func(src, dst string, sfi, dfi os.FileInfo) (bool, error) {
+		return sfi.ModTime().Sub(dfi.ModTime()) <= tolerance, nil
+	}
+}
+
+// CompareChecksum skip if size and content hash are same. h nil is sha256.
+func CompareChecksum(h func() hash.Hash) Compare {
+	return CompareChecksumFS(nil, h)
+}
+
+// CompareChecksumFS is CompareChecksum reading src from fsys, same as CopyOption.FS.
+// fsys nil is OsFS. dst is always read from OS filesystem.
+func CompareChecksumFS(fsys FileSystem, h func() hash.Hash) Compare {
+	if fsys == nil {
+		fsys = OsFS{}
+	}
+	if h == nil {
+		h = sha256.New
+	}
+	return func(src, dst string, sfi, dfi os.FileInfo) (bool, error) {
+		if sfi.Size() != dfi.Size() {
+			return false, nil
+		}
+		sd, err := hashFS(fsys, src, h())
+		if err != nil {
+			return false, err
+		}
+		dd, err := hashFile(dst, h())
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(sd, dd), nil
+	}
+}
+
+func withinTolerance(d, tolerance time.Duration) bool {
+	if d < 0 {
+		d = -d
+	}
+	return d <= tolerance
+}
+
+// hashFile return digest of path.
+func hashFile(path string, h hash.Hash) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	_, err = copyBuffer(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
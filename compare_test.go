@@ -0,0 +1,83 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCompare is test CopyWithOption func with Compare option.
+func TestCompare(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "file0")
+	dst := filepath.Join(tmp, "dir2", "file0")
+	ioutil.WriteFile(src, []byte("test"), os.ModePerm)
+	ioutil.WriteFile(dst, []byte("TEST"), os.ModePerm)
+	base := time.Now().Truncate(time.Second)
+	os.Chtimes(src, base, base)
+	// FAT like 2 seconds resolution.
+	os.Chtimes(dst, base.Add(time.Second), base.Add(time.Second))
+
+	tests := []struct {
+		name string
+		cmp  Compare
+		skip bool
+	}{
+		{"exact", CompareSizeTime(0), false},
+		{"tolerance", CompareSizeTime(2 * time.Second), true},
+		{"checksum", CompareChecksum(nil), false},
+		{"always", CompareAlways, false},
+		{"never", CompareNever, true},
+		{"newer", CompareNewer(0), true},
+	}
+	for _, tt := range tests {
+		r, err := CopyWithOption(src, dst, CopyOption{Compare: tt.cmp, Method: CopyBuffer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (r.Method == CopySkip) != tt.skip {
+			t.Fatalf("%s: Expected: skip [%v] but actual: [%v]\n", tt.name, tt.skip, r.Method)
+		}
+		// Restore dst.
+		ioutil.WriteFile(dst, []byte("TEST"), os.ModePerm)
+		os.Chtimes(dst, base.Add(time.Second), base.Add(time.Second))
+	}
+
+	// Same content is skipped by checksum even if mod time differ.
+	ioutil.WriteFile(dst, []byte("test"), os.ModePerm)
+	r, err := CopyWithOption(src, dst, CopyOption{Compare: CompareChecksum(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Method != CopySkip {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", CopySkip, r.Method)
+	}
+
+	// Src is read through FS.
+	ffs, err := NewFaultFS(nil, Fault{Match: `file0$`, Op: FaultOpen, Err: os.ErrPermission})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sfi, _ := os.Stat(src)
+	dfi, _ := os.Stat(dst)
+	if skip, err := CompareChecksum(nil)(src, dst, sfi, dfi); err != nil || !skip {
+		t.Fatalf("Expected: skip but actual: [%v] [%v]\n", skip, err)
+	}
+	if _, err := CompareChecksumFS(ffs, nil)(src, dst, sfi, dfi); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+
+	// Newer src is copied.
+	os.Chtimes(src, base.Add(time.Hour), base.Add(time.Hour))
+	r, err = CopyWithOption(src, dst, CopyOption{Compare: CompareNewer(2 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Method == CopySkip {
+		t.Fatalf("Expected: copied but actual: [%v]\n", r.Method)
+	}
+}
@@ -0,0 +1,492 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Preserve is set of metadata preserved by Copy.
+type Preserve uint
+
+const (
+	// PreserveMode is permission bits.
+	PreserveMode Preserve = 1 << iota
+	// PreserveTimes is mod time.
+	PreserveTimes
+	// PreserveAtime is access time.
+	PreserveAtime
+	// PreserveOwner is owner and group.
+	PreserveOwner
+	// PreserveXattr is extended attributes.
+	PreserveXattr
+	// PreserveACL is POSIX ACLs.
+	PreserveACL
+
+	// PreserveDefault is used when CopyOption.Preserve is 0.
+	PreserveDefault = PreserveMode | PreserveTimes
+	// PreserveAll is all metadata, same as `cp -p` with xattrs and ACLs.
+	PreserveAll = PreserveMode | PreserveTimes | PreserveAtime | PreserveOwner | PreserveXattr | PreserveACL
+)
+
+// CopyMethod is method used to copy file data.
+type CopyMethod string
+
+const (
+	// CopyReflink is FICLONE reflink (btrfs, XFS).
+	CopyReflink CopyMethod = "reflink"
+	// CopyFileRange is copy_file_range (server side copy on NFS4.2).
+	CopyFileRange CopyMethod = "copy_file_range"
+	// CopySendfile is sendfile.
+	CopySendfile CopyMethod = "sendfile"
+	// CopyBuffer is buffered read and write.
+	CopyBuffer CopyMethod = "buffer"
+	// CopySparse is buffered copy keeping holes.
+	CopySparse CopyMethod = "sparse"
+	// CopyDelta is in place update of changed blocks.
+	CopyDelta CopyMethod = "delta"
+	// CopySkip is skipped because dst is up to date.
+	CopySkip CopyMethod = "skip"
+)
+
+var errNoSeekData = errors.New("SEEK_DATA is not supported")
+
+// IOClass is ionice style I/O scheduling class.
+type IOClass int
+
+const (
+	// IOClassNone is not changed.
+	IOClassNone IOClass = iota
+	// IOClassRealtime is realtime class.
+	IOClassRealtime
+	// IOClassBestEffort is best effort class.
+	IOClassBestEffort
+	// IOClassIdle is idle class.
+	IOClassIdle
+)
+
+// CopyResult is result of CopyWithOption func.
+type CopyResult struct {
+	N      int64
+	Method CopyMethod
+	// Digest is hash of src computed while copying if CopyOption.Hash or Verify is set.
+	Digest []byte
+	// Resumed is bytes reused from partial dst by CopyOption.Resume.
+	Resumed int64
+	// Written is bytes written to dst. It is less than N by Resume and Delta.
+	Written int64
+}
+
+// CopyOption is option of CopyWithOption func.
+type CopyOption struct {
+	// Overwrite is always copy even if dst is up to date. Ignored if Compare is set.
+	Overwrite bool
+	// Compare decide whether existing dst is up to date.
+	// nil is CompareAlways if Overwrite, else CompareSizeTime(0).
+	// Use CompareChecksumFS with same FS to compare content read through FS.
+	Compare Compare
+	// Method is copy method to try first. Empty is reflink, copy_file_range,
+	// sendfile and buffer in order. Buffer is always used as fallback.
+	Method CopyMethod
+	// Sparse keep holes of src using SEEK_DATA/SEEK_HOLE or zero block detection.
+	// Only reflink is tried before sparse copy.
+	Sparse bool
+	// Preserve is metadata to preserve. 0 is PreserveDefault.
+	Preserve Preserve
+	// Hash is hash constructor to compute digest while copying, such as HashSHA256, HashXXH64 or HashBLAKE3.
+	// Kernel accelerated methods are not used when Hash is set.
+	Hash func() hash.Hash
+	// Verify re-read dst and compare digest before rename. Hash nil is HashSHA256.
+	Verify bool
+	// Resume keep partial dst on failure and continue from last checkpoint next time.
+	// Kernel accelerated methods and Sparse are not used when Resume is set.
+	Resume bool
+	// Limiter throttle read bandwidth. It can be shared by concurrent copies.
+	// Kernel accelerated methods are not used when Limiter is set.
+	Limiter *Limiter
+	// IOClass and IOLevel (0-7) are I/O priority hint (Linux only).
+	IOClass IOClass
+	IOLevel int
+	// Progress is called with progress at ProgressInterval (0 is 1 second) and on finish.
+	// Calls are serialized. Kernel accelerated methods are not used when Progress is set.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+	// Delta update only changed blocks of existing dst in place using rolling checksums.
+	// dst is not replaced atomically. DeltaBlockSize 0 is 64KiB.
+	Delta          bool
+	DeltaBlockSize int
+	// FS is filesystem to read src. nil is OsFS.
+	FS FileSystem
+
+	// tracker is shared by CopyTree to aggregate progress.
+	tracker *progressTracker
+}
+
+func (copt CopyOption) compare() Compare {
+	if copt.Compare != nil {
+		return copt.Compare
+	}
+	if copt.Overwrite {
+		return CompareAlways
+	}
+	return CompareSizeTime(0)
+}
+
+func (copt CopyOption) fileSystem() FileSystem {
+	if copt.FS == nil {
+		return OsFS{}
+	}
+	return copt.FS
+}
+
+// Copy is file copy.
+func Copy(src, dst string, overwrite bool) (int64, error) {
+	r, err := CopyWithOption(src, dst, CopyOption{Overwrite: overwrite})
+	if err != nil {
+		return -1, err
+	}
+	return r.N, nil
+}
+
+// CopyWithOption is atomic file copy.
+// src is written into temp file in dst directory, synced and renamed to dst,
+// so dst is never left truncated.
+func CopyWithOption(src, dst string, copt CopyOption) (CopyResult, error) {
+
+	var r CopyResult
+
+	fsys := copt.fileSystem()
+	src = filepath.FromSlash(src)
+	dst = filepath.FromSlash(dst)
+	fss, err := fsys.Stat(src)
+	if err != nil {
+		return r, err
+	}
+
+	finish := false
+	if copt.tracker == nil && copt.Progress != nil {
+		copt.tracker = newProgressTracker(copt.Progress, copt.ProgressInterval, fss.Size())
+		finish = true
+	}
+
+	if IsExist(dst) {
+		fds, err := os.Stat(dst)
+		if err != nil {
+			return r, err
+		}
+
+		skip, err := copt.compare()(src, dst, fss, fds)
+		if err != nil {
+			return r, err
+		}
+		if skip {
+			r.Method = CopySkip
+			copt.tracker.add(src, fss.Size())
+			if finish {
+				copt.tracker.finish()
+			}
+			return r, nil
+		}
+	}
+
+	fs, err := fsys.Open(src)
+	if err != nil {
+		return r, err
+	}
+	defer fs.Close()
+
+	var (
+		ds    *os.File
+		delta = copt.Delta && IsExistFile(dst)
+	)
+	switch {
+	case delta:
+		ds, err = os.OpenFile(dst, os.O_RDWR, 0)
+	case copt.Resume:
+		ds, err = os.OpenFile(partPath(dst), os.O_RDWR|os.O_CREATE, 0666)
+	default:
+		ds, err = createTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".")
+	}
+	if err != nil {
+		return r, err
+	}
+	tmp := ds.Name()
+	defer func() {
+		// Remove temp file if not renamed. Partial file is kept for resume.
+		if tmp != "" {
+			ds.Close()
+			if !copt.Resume && !delta {
+				os.Remove(tmp)
+			}
+		}
+	}()
+
+	// Hash while streaming.
+	var (
+		h  hash.Hash
+		in io.Reader = fs
+	)
+	if copt.Verify && copt.Hash == nil {
+		copt.Hash = HashSHA256
+	}
+	if copt.Hash != nil {
+		h = copt.Hash()
+		in = io.TeeReader(fs, h)
+	}
+	in = copt.tracker.reader(src, copt.Limiter.Reader(in))
+
+	// I/O priority is per thread.
+	if copt.IOClass != IOClassNone {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		restore, err := setIOPriority(copt.IOClass, copt.IOLevel)
+		if err != nil {
+			return r, err
+		}
+		defer restore()
+	}
+
+	switch {
+	case delta:
+		r.N, r.Written, err = copyDelta(ds, in, copt.DeltaBlockSize)
+		r.Method = CopyDelta
+	case copt.Resume:
+		r.N, r.Resumed, err = copyResume(ds, fs, src, fss, h, copt)
+		r.Written = r.N - r.Resumed
+		r.Method = CopyBuffer
+	default:
+		r.N, r.Method, err = copyData(ds, in, fss.Size(), copt)
+		r.Written = r.N
+	}
+	if err != nil {
+		return r, err
+	}
+
+	err = ds.Sync()
+	if err != nil {
+		return r, err
+	}
+
+	if h != nil {
+		r.Digest = h.Sum(nil)
+		if copt.Verify {
+			err = verify(ds, copt.Hash(), r.Digest)
+			if err != nil {
+				return r, err
+			}
+		}
+	}
+	err = ds.Close()
+	if err != nil {
+		return r, err
+	}
+
+	err = preserveMeta(src, tmp, fss, copt.Preserve)
+	if err != nil {
+		return r, err
+	}
+
+	if !delta {
+		err = os.Rename(tmp, dst)
+		if err != nil {
+			return r, err
+		}
+	}
+	tmp = ""
+	if copt.Resume {
+		os.Remove(statePath(dst))
+	}
+	if finish {
+		copt.tracker.finish()
+	}
+
+	syncDir(filepath.Dir(dst))
+	return r, nil
+}
+
+// copyData copy fs to ds with kernel accelerated methods if possible,
+// and fallback to buffered copy.
+func copyData(ds *os.File, fs io.Reader, size int64, copt CopyOption) (int64, CopyMethod, error) {
+	var n int64
+	f, ok := fs.(*os.File)
+
+	if copt.Sparse {
+		if ok && (copt.Method == "" || copt.Method == CopyReflink) {
+			n, used, handled, err := copyKernel(ds, f, size, CopyReflink)
+			if handled {
+				return n, used, err
+			}
+		}
+		n, err := copySparse(ds, fs, size)
+		return n, CopySparse, err
+	}
+
+	if ok && copt.Method != CopyBuffer {
+		var (
+			used    CopyMethod
+			handled bool
+			err     error
+		)
+		n, used, handled, err = copyKernel(ds, f, size, copt.Method)
+		if err != nil {
+			return n, used, err
+		}
+		if used == CopyReflink {
+			return n, used, nil
+		}
+		if handled {
+			// Copy rest if file grown.
+			m, err := copyBuffer(ds, fs)
+			return n + m, used, err
+		}
+	}
+	m, err := copyBuffer(ds, fs)
+	return n + m, CopyBuffer, err
+}
+
+// copySparse copy fs to ds keeping holes.
+func copySparse(ds *os.File, fs io.Reader, size int64) (int64, error) {
+	if f, ok := fs.(*os.File); ok {
+		n, err := copySeekData(ds, f, size)
+		if err != errNoSeekData {
+			return n, err
+		}
+	}
+	return copyZeroBlocks(ds, fs)
+}
+
+// copyZeroBlocks copy fs to ds, and seek over zero blocks instead of writing them.
+func copyZeroBlocks(ds *os.File, fs io.Reader) (int64, error) {
+	var (
+		n     int64
+		buf   = make([]byte, 128*1024)
+		block = 4096
+	)
+	for {
+		m, err := io.ReadFull(fs, buf)
+		for off := 0; off < m; off += block {
+			end := off + block
+			if end > m {
+				end = m
+			}
+			if isZero(buf[off:end]) {
+				if _, err := ds.Seek(int64(end-off), io.SeekCurrent); err != nil {
+					return n, err
+				}
+			} else if _, err := ds.Write(buf[off:end]); err != nil {
+				return n, err
+			}
+			n += int64(end - off)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	// Make trailing hole.
+	return n, ds.Truncate(n)
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// copyBuffer is io.Copy without ReaderFrom and WriterTo, so it never use kernel copy.
+func copyBuffer(dst io.Writer, src io.Reader) (int64, error) {
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, make([]byte, 128*1024))
+}
+
+// verify re-read f from start and compare digest.
+func verify(f *os.File, h hash.Hash, digest []byte) error {
+	_, err := f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	_, err = copyBuffer(h, f)
+	if err != nil {
+		return err
+	}
+	if d := h.Sum(nil); !bytes.Equal(d, digest) {
+		return fmt.Errorf("[%s] checksum mismatch: expected [%x] but actual [%x]", f.Name(), digest, d)
+	}
+	return nil
+}
+
+// syncDir sync directory entry for rename durability. Errors are ignored
+// because some platforms can not sync directory.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// createTemp create unique file in dir like ioutil.TempFile, but with mode 0666 before umask like os.Create.
+// So dst has default mode if PreserveMode is not set.
+func createTemp(dir, prefix string) (*os.File, error) {
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &os.PathError{Op: "createtemp", Path: filepath.Join(dir, prefix+"*"), Err: os.ErrExist}
+}
+
+// preserveMeta copy metadata of src (fi) to dst.
+func preserveMeta(src, dst string, fi os.FileInfo, p Preserve) error {
+	if p == 0 {
+		p = PreserveDefault
+	}
+
+	// Owner and xattrs first, chown may clear setuid bits.
+	err := preserveSys(src, dst, fi, p)
+	if err != nil {
+		return err
+	}
+
+	if p&PreserveMode != 0 {
+		err = os.Chmod(dst, fi.Mode().Perm()|fi.Mode()&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+		if err != nil {
+			return err
+		}
+	}
+
+	if p&(PreserveTimes|PreserveAtime) != 0 {
+		atime, mtime := time.Now(), fi.ModTime()
+		if p&PreserveAtime != 0 {
+			atime = accessTime(fi)
+		}
+		if p&PreserveTimes == 0 {
+			dfi, err := os.Stat(dst)
+			if err != nil {
+				return err
+			}
+			mtime = dfi.ModTime()
+		}
+		err = os.Chtimes(dst, atime, mtime)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
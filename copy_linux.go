@@ -0,0 +1,212 @@
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+var (
+	// sysCopyFileRange is copy_file_range syscall number. 0 is not supported.
+	sysCopyFileRange = map[string]uintptr{
+		"386":     377,
+		"amd64":   326,
+		"arm":     391,
+		"arm64":   285,
+		"loong64": 285,
+		"ppc64":   379,
+		"ppc64le": 379,
+		"riscv64": 285,
+		"s390x":   375,
+	}[runtime.GOARCH]
+
+	// ficlone is FICLONE ioctl request.
+	ficlone = func() uintptr {
+		if strings.HasPrefix(runtime.GOARCH, "ppc") || strings.HasPrefix(runtime.GOARCH, "mips") {
+			return 0x80049409
+		}
+		return 0x40049409
+	}()
+)
+
+// copyKernel copy fs to ds with kernel accelerated methods.
+// handled is false if no method was available and nothing was copied.
+func copyKernel(ds, fs *os.File, size int64, method CopyMethod) (n int64, used CopyMethod, handled bool, err error) {
+	if method == "" || method == CopyReflink {
+		err = reflink(ds, fs)
+		if err == nil {
+			return size, CopyReflink, true, nil
+		}
+		if !isUnsupported(err) {
+			return 0, CopyReflink, true, err
+		}
+	}
+
+	if method == "" || method == CopyFileRange {
+		n, err = copyFileRange(ds, fs, size)
+		if err == nil {
+			return n, CopyFileRange, true, nil
+		}
+		if n != 0 || !isUnsupported(err) {
+			return n, CopyFileRange, true, err
+		}
+	}
+
+	if method == "" || method == CopySendfile {
+		n, err = sendfile(ds, fs, size)
+		if err == nil {
+			return n, CopySendfile, true, nil
+		}
+		if n != 0 || !isUnsupported(err) {
+			return n, CopySendfile, true, err
+		}
+	}
+
+	return 0, "", false, nil
+}
+
+// isUnsupported is whether err means method is not available for the files.
+func isUnsupported(err error) bool {
+	switch err {
+	case syscall.ENOSYS, syscall.EOPNOTSUPP, syscall.EXDEV, syscall.EINVAL, syscall.ENOTTY, syscall.EPERM, syscall.EBADF:
+		return true
+	}
+	return false
+}
+
+func reflink(ds, fs *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, ds.Fd(), ficlone, fs.Fd())
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func copyFileRange(ds, fs *os.File, size int64) (int64, error) {
+	if sysCopyFileRange == 0 {
+		return 0, syscall.ENOSYS
+	}
+	var written int64
+	for written < size {
+		chunk := size - written
+		if chunk > 1<<30 {
+			chunk = 1 << 30
+		}
+		r, _, errno := syscall.Syscall6(sysCopyFileRange, fs.Fd(), 0, ds.Fd(), 0, uintptr(chunk), 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return written, errno
+		}
+		if r == 0 {
+			// File was truncated.
+			break
+		}
+		written += int64(r)
+	}
+	return written, nil
+}
+
+func sendfile(ds, fs *os.File, size int64) (int64, error) {
+	var written int64
+	for written < size {
+		chunk := size - written
+		if chunk > 1<<30 {
+			chunk = 1 << 30
+		}
+		r, err := syscall.Sendfile(int(ds.Fd()), int(fs.Fd()), nil, int(chunk))
+		if err == syscall.EINTR || err == syscall.EAGAIN {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		if r == 0 {
+			break
+		}
+		written += int64(r)
+	}
+	return written, nil
+}
+
+const (
+	seekData = 3
+	seekHole = 4
+)
+
+// copySeekData copy only data segments of fs using SEEK_DATA/SEEK_HOLE.
+// Return errNoSeekData if filesystem not support it.
+func copySeekData(ds, fs *os.File, size int64) (int64, error) {
+	var n, off int64
+	for off < size {
+		data, err := fs.Seek(off, seekData)
+		if isErrno(err, syscall.ENXIO) {
+			// No more data.
+			break
+		}
+		if isErrno(err, syscall.EINVAL) && off == 0 {
+			return 0, errNoSeekData
+		}
+		if err != nil {
+			return n, err
+		}
+		hole, err := fs.Seek(data, seekHole)
+		if err != nil {
+			return n, err
+		}
+		if hole > size {
+			hole = size
+		}
+		if _, err = fs.Seek(data, io.SeekStart); err != nil {
+			return n, err
+		}
+		if _, err = ds.Seek(data, io.SeekStart); err != nil {
+			return n, err
+		}
+		m, err := copyBuffer(ds, io.LimitReader(fs, hole-data))
+		n += m
+		if err != nil {
+			return n, err
+		}
+		off = hole
+	}
+	// Make trailing hole.
+	if err := ds.Truncate(size); err != nil {
+		return n, err
+	}
+	return size, nil
+}
+
+func isErrno(err error, errno syscall.Errno) bool {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	return err == errno
+}
+
+// reflinkFile create dst as reflink of src.
+func reflinkFile(src, dst string) error {
+	fs, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+	ds, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	err = reflink(ds, fs)
+	if cerr := ds.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// hiddenFS is OsFS which hide *os.File from Open.
+type hiddenFS struct {
+	OsFS
+}
+
+func (fs hiddenFS) Open(name string) (io.ReadCloser, error) {
+	f, err := fs.OsFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ io.ReadCloser }{f}, nil
+}
+
+func blocks(t *testing.T, path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Sys().(*syscall.Stat_t).Blocks
+}
+
+// TestCopySparse is test CopyWithOption func with Sparse option.
+func TestCopySparse(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	// 64KiB data, 8MiB hole, 4KiB data, 1MiB trailing hole.
+	src := filepath.Join(tmp, "sparse")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(bytes.Repeat([]byte{'a'}, 64*1024))
+	f.Seek(8*1024*1024, 1)
+	f.Write(bytes.Repeat([]byte{'b'}, 4096))
+	f.Truncate(64*1024 + 8*1024*1024 + 4096 + 1024*1024)
+	f.Close()
+
+	sb := blocks(t, src)
+	if sb*512 >= 1024*1024 {
+		t.Skip("filesystem does not support sparse file")
+	}
+	b, _ := ioutil.ReadFile(src)
+
+	for _, copt := range []CopyOption{
+		// No reflink, so SEEK_DATA/SEEK_HOLE is used.
+		{Sparse: true, Method: CopyBuffer},
+		// hiddenFS hide *os.File, so zero block detection is used.
+		{Sparse: true, FS: hiddenFS{}},
+	} {
+		dst := filepath.Join(tmp, "dir2", "sparse")
+		r, err := CopyWithOption(src, dst, copt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.N != int64(len(b)) {
+			t.Fatalf("Expected: [%d] but actual: [%d]\n", len(b), r.N)
+		}
+		a, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(a, b) {
+			t.Fatalf("Expected: same content [%v]\n", dst)
+		}
+		db := blocks(t, dst)
+		t.Log(r.Method, sb, db)
+		if r.Method != CopySparse || db > sb+8 {
+			t.Fatalf("Expected: [%v] [%d] blocks but actual: [%v] [%d] blocks\n", CopySparse, sb, r.Method, db)
+		}
+		os.Remove(dst)
+	}
+
+	// Trailing hole not aligned to block with zero block detection.
+	tail := filepath.Join(tmp, "tail")
+	f, err = os.Create(tail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(bytes.Repeat([]byte{'c'}, 4096))
+	f.Truncate(4096 + 3*1024*1024 + 123)
+	f.Close()
+	tb, _ := ioutil.ReadFile(tail)
+	dst := filepath.Join(tmp, "dir2", "tail")
+	r, err := CopyWithOption(tail, dst, CopyOption{Sparse: true, FS: hiddenFS{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := ioutil.ReadFile(dst)
+	if r.Method != CopySparse || r.N != int64(len(tb)) || !bytes.Equal(a, tb) {
+		t.Fatalf("Expected: [%v] [%d] and same content but actual: [%v] [%d]\n", CopySparse, len(tb), r.Method, len(a))
+	}
+	if db := blocks(t, dst); db*512 >= 1024*1024 {
+		t.Fatalf("Expected: under [%d] but actual: [%d]\n", 1024*1024, db*512)
+	}
+
+	// Buffered copy inflate.
+	dst = filepath.Join(tmp, "dir2", "full")
+	if _, err := CopyWithOption(src, dst, CopyOption{Method: CopyBuffer}); err != nil {
+		t.Fatal(err)
+	}
+	if db := blocks(t, dst); db*512 < int64(len(b)) {
+		t.Fatalf("Expected: over [%d] but actual: [%d]\n", len(b), db*512)
+	}
+}
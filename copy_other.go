@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// copyKernel is not supported on this platform.
+func copyKernel(ds, fs *os.File, size int64, method CopyMethod) (n int64, used CopyMethod, handled bool, err error) {
+	return 0, "", false, nil
+}
+
+// copySeekData is not supported on this platform.
+func copySeekData(ds, fs *os.File, size int64) (int64, error) {
+	return 0, errNoSeekData
+}
+
+// reflinkFile is not supported on this platform.
+func reflinkFile(src, dst string) error {
+	return fmt.Errorf("reflink is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,309 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCopy is test Copy func.
+func TestCopy(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir0", "file1")
+	dst := filepath.Join(tmp, "dir2", "file1")
+	ioutil.WriteFile(src, []byte("test"), 0640)
+	os.Chmod(src, 0640)
+
+	n, err := Copy(src, dst, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 4, n)
+	}
+	sfi, _ := os.Stat(src)
+	dfi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sfi.ModTime().Equal(dfi.ModTime()) || dfi.Mode().Perm() != 0640 {
+		t.Fatalf("Expected: [%v] [%v] but actual: [%v] [%v]\n", sfi.ModTime(), os.FileMode(0640), dfi.ModTime(), dfi.Mode().Perm())
+	}
+
+	// Same size and mod time is skipped.
+	n, err = Copy(src, dst, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 0, n)
+	}
+}
+
+// TestCopyDefaultMode is test dst has default mode like os.Create without PreserveMode.
+func TestCopyDefaultMode(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "file0")
+	os.Chmod(src, 0600)
+	// Mode of os.Create is 0666 minus umask.
+	ref := filepath.Join(tmp, "dir2", "ref")
+	f, err := os.Create(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	rfi, _ := os.Stat(ref)
+
+	for _, copt := range []CopyOption{{Preserve: PreserveTimes}, {Preserve: PreserveTimes, Resume: true}} {
+		dst := filepath.Join(tmp, "dir2", "file0")
+		if _, err := CopyWithOption(src, dst, copt); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode() != rfi.Mode() {
+			t.Fatalf("Expected: [%v] but actual: [%v]\n", rfi.Mode(), fi.Mode())
+		}
+		os.Remove(dst)
+	}
+}
+
+// TestCopyAtomic is test CopyWithOption func failed partway through.
+func TestCopyAtomic(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "file0")
+	dst := filepath.Join(tmp, "dir2", "file0")
+	ioutil.WriteFile(src, []byte("new content"), os.ModePerm)
+
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(src), Op: FaultOpen, Err: os.ErrClosed, Partial: 3, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// New dst is not created.
+	if _, err := CopyWithOption(src, dst, CopyOption{FS: ffs}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+	if IsExist(dst) {
+		t.Fatalf("Expected: [%v] is not exist\n", dst)
+	}
+	fis, _ := ioutil.ReadDir(filepath.Dir(dst))
+	if len(fis) != 0 {
+		t.Fatalf("Expected: no temp file but actual: [%v]\n", fis[0].Name())
+	}
+
+	// Later run copies whole file.
+	r, err := CopyWithOption(src, dst, CopyOption{FS: ffs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.N != 11 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 11, r.N)
+	}
+
+	// Existing dst is kept.
+	ioutil.WriteFile(src, []byte("newer content"), os.ModePerm)
+	ffs, err = NewFaultFS(nil, Fault{Match: pathRe(src), Op: FaultOpen, Err: os.ErrClosed, Partial: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CopyWithOption(src, dst, CopyOption{Overwrite: true, FS: ffs}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+	b, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "new content" {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", "new content", string(b))
+	}
+	fis, _ = ioutil.ReadDir(filepath.Dir(dst))
+	if len(fis) != 1 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 1, len(fis))
+	}
+}
+
+func writeRandom(path string, size int) []byte {
+	b := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(b)
+	ioutil.WriteFile(path, b, os.ModePerm)
+	return b
+}
+
+// TestCopyMethod is test CopyWithOption func with each Method.
+func TestCopyMethod(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "file0")
+	b := writeRandom(src, 1024*1024+123)
+
+	for _, m := range []CopyMethod{"", CopyReflink, CopyFileRange, CopySendfile, CopyBuffer} {
+		dst := filepath.Join(tmp, "dir2", "file0"+string(m))
+		r, err := CopyWithOption(src, dst, CopyOption{Method: m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Log(m, r.Method)
+		if m != "" && r.Method != m && r.Method != CopyBuffer {
+			t.Fatalf("Expected: [%v] or [%v] but actual: [%v]\n", m, CopyBuffer, r.Method)
+		}
+		if r.N != int64(len(b)) {
+			t.Fatalf("Expected: [%d] but actual: [%d]\n", len(b), r.N)
+		}
+		a, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(a, b) {
+			t.Fatalf("Expected: same content [%v] method: [%v]\n", dst, r.Method)
+		}
+	}
+}
+
+func benchmarkCopy(b *testing.B, fn func(src, dst string) error) {
+	tmp, err := ioutil.TempDir("", "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer shutdown(tmp)
+
+	size := 16 * 1024 * 1024
+	src := filepath.Join(tmp, "src")
+	writeRandom(src, size)
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := fn(src, filepath.Join(tmp, "dst")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCopyIoCopy is benchmark of previous io.Copy implementation.
+func BenchmarkCopyIoCopy(b *testing.B) {
+	benchmarkCopy(b, func(src, dst string) error {
+		fs, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer fs.Close()
+		ds, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		defer ds.Close()
+		_, err = io.Copy(ds, fs)
+		return err
+	})
+}
+
+func benchmarkCopyMethod(b *testing.B, m CopyMethod) {
+	benchmarkCopy(b, func(src, dst string) error {
+		_, err := CopyWithOption(src, dst, CopyOption{Overwrite: true, Method: m})
+		return err
+	})
+}
+
+// BenchmarkCopyAuto is benchmark of Copy with auto method.
+func BenchmarkCopyAuto(b *testing.B) { benchmarkCopyMethod(b, "") }
+
+// BenchmarkCopyReflink is benchmark of Copy with reflink.
+func BenchmarkCopyReflink(b *testing.B) { benchmarkCopyMethod(b, CopyReflink) }
+
+// BenchmarkCopyFileRange is benchmark of Copy with copy_file_range.
+func BenchmarkCopyFileRange(b *testing.B) { benchmarkCopyMethod(b, CopyFileRange) }
+
+// BenchmarkCopySendfile is benchmark of Copy with sendfile.
+func BenchmarkCopySendfile(b *testing.B) { benchmarkCopyMethod(b, CopySendfile) }
+
+// BenchmarkCopyBuffer is benchmark of Copy with buffer.
+func BenchmarkCopyBuffer(b *testing.B) { benchmarkCopyMethod(b, CopyBuffer) }
+
+// TestHash is test hash constructors with known digests.
+func TestHash(t *testing.T) {
+	for _, tt := range []struct {
+		h    func() hash.Hash
+		want string
+	}{
+		{HashSHA256, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{HashXXH64, "44bc2cf5ad770999"},
+		{HashBLAKE3, "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+	} {
+		h := tt.h()
+		h.Write([]byte("abc"))
+		if actual := fmt.Sprintf("%x", h.Sum(nil)); actual != tt.want {
+			t.Fatalf("Expected: [%s] but actual: [%s]\n", tt.want, actual)
+		}
+	}
+}
+
+// TestCopyVerify is test CopyWithOption func with Hash and Verify option.
+func TestCopyVerify(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "file0")
+	dst := filepath.Join(tmp, "dir2", "file0")
+	b := writeRandom(src, 300*1024)
+	sum := sha256.Sum256(b)
+
+	r, err := CopyWithOption(src, dst, CopyOption{Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r.Digest, sum[:]) || r.Method != CopyBuffer {
+		t.Fatalf("Expected: [%x] [%v] but actual: [%x] [%v]\n", sum, CopyBuffer, r.Digest, r.Method)
+	}
+
+	// Custom hash without verify.
+	r, err = CopyWithOption(src, dst, CopyOption{Overwrite: true, Hash: func() hash.Hash { return crc32.NewIEEE() }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := crc32.ChecksumIEEE(b); binary.BigEndian.Uint32(r.Digest) != c {
+		t.Fatalf("Expected: [%x] but actual: [%x]\n", c, r.Digest)
+	}
+
+	// Non-cryptographic hash with verify.
+	r, err = CopyWithOption(src, dst, CopyOption{Overwrite: true, Hash: HashXXH64, Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := HashXXH64()
+	h.Write(b)
+	if !bytes.Equal(r.Digest, h.Sum(nil)) || len(r.Digest) != 8 {
+		t.Fatalf("Expected: [%x] but actual: [%x]\n", h.Sum(nil), r.Digest)
+	}
+
+	// Mismatch keep dst.
+	ioutil.WriteFile(src, []byte("changed"), os.ModePerm)
+	seed := uint32(0)
+	broken := func() hash.Hash {
+		seed++
+		h := crc32.NewIEEE()
+		binary.Write(h, binary.BigEndian, seed)
+		return h
+	}
+	if _, err := CopyWithOption(src, dst, CopyOption{Overwrite: true, Hash: broken, Verify: true}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+	a, _ := ioutil.ReadFile(dst)
+	if !bytes.Equal(a, b) {
+		t.Fatalf("Expected: [%v] is not changed\n", dst)
+	}
+}
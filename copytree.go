@@ -0,0 +1,171 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CopyInfo is result of each file copied by CopyTree.
+type CopyInfo struct {
+	Src string
+	Dst string
+	CopyResult
+	Err error
+}
+
+// CopyTree copy directory hierarchy src to dst with parallel workers.
+// Files are filtered by opt same as GetInfos, and copied by CopyWithOption with copt.
+// Directories metadata are preserved same as files, and copt.Progress is aggregated over all files.
+func CopyTree(src, dst string, opt Option, copt CopyOption) (chan CopyInfo, error) {
+	var (
+		mu   sync.Mutex
+		dirs = map[string]bool{}
+
+		wg = new(sync.WaitGroup)
+		q  = make(chan CopyInfo, 20)
+	)
+
+	src, dst, err := checkTree(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	opt = opt.realFiles()
+
+	// Total size for aggregated progress.
+	if copt.Progress != nil {
+		files, err := GetFiles(src, opt)
+		if err != nil {
+			return nil, err
+		}
+		var total int64
+		for f := range files {
+			if f.Err == nil {
+				total += f.Fi.Size()
+			}
+		}
+		copt.tracker = newProgressTracker(copt.Progress, copt.ProgressInterval, total)
+	}
+
+	infos, err := GetInfos(src, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// mkdir create directory and all parents, and remember them to restore metadata.
+	mkdir := func(rel string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for d := rel; d != "." && !dirs[d]; d = filepath.Dir(d) {
+			dirs[d] = true
+		}
+		return os.MkdirAll(filepath.Join(dst, rel), os.ModePerm)
+	}
+
+	worker := func() {
+		defer wg.Done()
+		for info := range infos {
+			ci := CopyInfo{Src: info.Path}
+			if info.Err != nil {
+				ci.Err = info.Err
+				q <- ci
+				continue
+			}
+			rel, err := filepath.Rel(src, info.Path)
+			if err != nil {
+				ci.Err = err
+				q <- ci
+				continue
+			}
+			ci.Dst = filepath.Join(dst, rel)
+			if info.Fi.IsDir() {
+				if ci.Err = mkdir(rel); ci.Err != nil {
+					q <- ci
+				}
+				continue
+			}
+			if ci.Err = mkdir(filepath.Dir(rel)); ci.Err != nil {
+				q <- ci
+				continue
+			}
+			ci.CopyResult, ci.Err = CopyWithOption(ci.Src, ci.Dst, copt)
+			q <- ci
+		}
+	}
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go worker()
+	}
+
+	// Restore directories metadata after all files copied, deepest first.
+	go func() {
+		wg.Wait()
+		copt.tracker.finish()
+		preserveDirs(src, dst, dirs, copt.Preserve, func(s, d string, err error) {
+			q <- CopyInfo{Src: s, Dst: d, Err: err}
+		})
+		close(q)
+	}()
+
+	return q, nil
+}
+
+// checkTree check src is directory and dst is not under src, and return cleaned paths.
+func checkTree(src, dst string) (string, string, error) {
+	src = filepath.Clean(filepath.FromSlash(src))
+	dst = filepath.Clean(filepath.FromSlash(dst))
+
+	// Check exist.
+	if !IsExistDir(src) {
+		return src, dst, fmt.Errorf("[%s] is not a directory", src)
+	}
+
+	// Check dst is not under src.
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return src, dst, err
+	}
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return src, dst, err
+	}
+	if absDst == absSrc || strings.HasPrefix(absDst, absSrc+string(filepath.Separator)) {
+		return src, dst, fmt.Errorf("[%s] is under [%s]", dst, src)
+	}
+	return src, dst, nil
+}
+
+// preserveDirs restore metadata of dirs (relative paths) and root, deepest first.
+func preserveDirs(src, dst string, dirs map[string]bool, p Preserve, onErr func(s, d string, err error)) {
+	rels := make([]string, 0, len(dirs)+1)
+	for d := range dirs {
+		rels = append(rels, d)
+	}
+	sortReverse(rels)
+	rels = append(rels, ".")
+	for _, rel := range rels {
+		s, d := filepath.Join(src, rel), filepath.Join(dst, rel)
+		fi, err := os.Stat(s)
+		if err == nil {
+			err = preserveMeta(s, d, fi, p)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			onErr(s, d, err)
+		}
+	}
+}
+
+// sortReverse sort paths so that children come before parents.
+func sortReverse(paths []string) {
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+}
@@ -0,0 +1,96 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCopyTree is test CopyTree func.
+func TestCopyTree(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir0")
+	dst := filepath.Join(tmp, "copy")
+	foo := filepath.Join(src, "foo", "bar")
+	ioutil.WriteFile(foo, []byte{'t', 'e', 's', 't'}, 0600)
+	os.Chmod(foo, 0600)
+	old := time.Now().Add(-5 * 24 * time.Hour).Truncate(time.Second)
+	os.Chtimes(filepath.Join(src, "foo"), old, old)
+
+	cis, err := CopyTree(src, dst, Option{Recurse: true}, CopyOption{Overwrite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for ci := range cis {
+		t.Log(ci.Src, ci.Dst, ci.N)
+		if ci.Err != nil {
+			t.Fatal(ci.Err)
+		}
+		cnt++
+	}
+	exp := 5
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+
+	// Same tree.
+	exp = getCnt(GetInfos, src, Option{Recurse: true}, t)
+	cnt = getCnt(GetInfos, dst, Option{Recurse: true}, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+
+	fi, err := os.Stat(filepath.Join(dst, "foo", "bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 || fi.Size() != 4 {
+		t.Fatalf("Expected: [%v] [%d] but actual: [%v] [%d]\n", os.FileMode(0600), 4, fi.Mode().Perm(), fi.Size())
+	}
+	fi, err = os.Stat(filepath.Join(dst, "foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(old) {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", old, fi.ModTime())
+	}
+}
+
+// TestCopyTreeOption is test CopyTree func with filter option.
+func TestCopyTreeOption(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	dst := filepath.Join(tmp, "..", filepath.Base(tmp)+"-copy")
+	defer shutdown(dst)
+
+	cis, err := CopyTree(tmp, dst, Option{Recurse: true, Matches: []string{`foo$`}}, CopyOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ci := range cis {
+		if ci.Err != nil {
+			t.Fatal(ci.Err)
+		}
+	}
+
+	// dir0/bar/foo, dir1/foo and dir0/foo (dir).
+	exp := 2
+	cnt := getCnt(GetFiles, dst, Option{Recurse: true}, t)
+	if cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+	if !IsExistDir(filepath.Join(dst, "dir0", "foo")) {
+		t.Fatalf("Expected: [%v] is directory\n", filepath.Join(dst, "dir0", "foo"))
+	}
+
+	// dst under src.
+	if _, err := CopyTree(tmp, filepath.Join(tmp, "dir2"), Option{}, CopyOption{}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
@@ -0,0 +1,266 @@
+package file
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DupReplace is how FindDuplicates replace duplicates.
+type DupReplace string
+
+const (
+	// DupReport only report duplicates.
+	DupReport DupReplace = ""
+	// DupHardlink replace duplicates with hard links to first file.
+	DupHardlink DupReplace = "hardlink"
+	// DupReflink replace duplicates with reflinks of first file (Linux btrfs, XFS only).
+	DupReflink DupReplace = "reflink"
+)
+
+// DupOption is option of FindDuplicates func.
+type DupOption struct {
+	// Hash is hash constructor. nil is sha256.
+	Hash func() hash.Hash
+	// PartialSize is bytes hashed from head of file before full hash. 0 is 4KiB.
+	PartialSize int64
+	// MinSize ignore files smaller than MinSize. 0 is 1, empty files are ignored.
+	MinSize int64
+	// Replace is how to replace duplicates.
+	Replace DupReplace
+}
+
+// DupGroup is group of files which have same content.
+type DupGroup struct {
+	Size   int64
+	Digest []byte
+	// Files is same content files. Files[0] is kept as original.
+	Files []Info
+	// Replaced is duplicates replaced by DupOption.Replace.
+	Replaced []Info
+	// Err is error of walk, hash or replace. Files has failed file if group is not found.
+	Err error
+}
+
+// FindDuplicates find same content files under roots. Files are grouped by size, partial hash and full hash,
+// and groups are sent as soon as confirmed. Hard linked files are counted once.
+func FindDuplicates(roots []string, opt Option, dopt DupOption) (chan DupGroup, error) {
+	if dopt.Hash == nil {
+		dopt.Hash = sha256.New
+	}
+	if dopt.PartialSize <= 0 {
+		dopt.PartialSize = 4 * 1024
+	}
+	if dopt.MinSize <= 0 {
+		dopt.MinSize = 1
+	}
+	switch dopt.Replace {
+	case DupReport, DupHardlink, DupReflink:
+	default:
+		return nil, fmt.Errorf("unknown replace [%s]", dopt.Replace)
+	}
+
+	opt = opt.realFiles()
+	if _, err := compileRegexps(opt); err != nil {
+		return nil, err
+	}
+
+	q := make(chan DupGroup)
+	go func() {
+		defer close(q)
+
+		bySize := map[int64][]Info{}
+		for _, root := range roots {
+			files, err := GetFiles(root, opt)
+			if err != nil {
+				q <- DupGroup{Files: []Info{{Path: root, Err: err}}, Err: err}
+				continue
+			}
+			for f := range files {
+				if f.Err != nil {
+					q <- DupGroup{Files: []Info{f}, Err: f.Err}
+					continue
+				}
+				if !f.Fi.Mode().IsRegular() || f.Fi.Size() < dopt.MinSize {
+					continue
+				}
+				bySize[f.Fi.Size()] = append(bySize[f.Fi.Size()], f)
+			}
+		}
+
+		// Largest first, it reclaim most space.
+		sizes := make([]int64, 0, len(bySize))
+		for size, fs := range bySize {
+			if len(fs) > 1 {
+				sizes = append(sizes, size)
+			}
+		}
+		sort.Slice(sizes, func(i, j int) bool { return sizes[i] > sizes[j] })
+
+		for _, size := range sizes {
+			for _, g := range findDupsOfSize(unlinked(bySize[size]), size, dopt, q) {
+				if dopt.Replace != DupReport {
+					replaceDups(&g, dopt.Replace)
+				}
+				q <- g
+			}
+		}
+	}()
+	return q, nil
+}
+
+// unlinked remove files which are same file (hard link) as previous one.
+// Files are keyed by device and inode, or compared by os.SameFile among same mtime if not available.
+func unlinked(files []Info) []Info {
+	res := files[:0]
+	ids := map[[2]uint64]bool{}
+	byTime := map[int64][]os.FileInfo{}
+	for _, f := range files {
+		if id, ok := fileID(f.Fi); ok {
+			if ids[id] {
+				continue
+			}
+			ids[id] = true
+			res = append(res, f)
+			continue
+		}
+		// Hard links share mtime.
+		t := f.Fi.ModTime().UnixNano()
+		same := false
+		for _, fi := range byTime[t] {
+			if os.SameFile(f.Fi, fi) {
+				same = true
+				break
+			}
+		}
+		if !same {
+			byTime[t] = append(byTime[t], f.Fi)
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// findDupsOfSize group same size files by partial and full hash. Hash errors are sent to q.
+func findDupsOfSize(files []Info, size int64, dopt DupOption, q chan DupGroup) []DupGroup {
+	if len(files) < 2 {
+		return nil
+	}
+
+	group := func(files []Info, limit int64) []DupGroup {
+		byDigest := map[string][]Info{}
+		var keys []string
+		for _, f := range files {
+			d, err := hashHead(f.Path, dopt.Hash(), limit)
+			if err != nil {
+				f.Err = err
+				q <- DupGroup{Files: []Info{f}, Err: err}
+				continue
+			}
+			k := string(d)
+			if _, ok := byDigest[k]; !ok {
+				keys = append(keys, k)
+			}
+			byDigest[k] = append(byDigest[k], f)
+		}
+		var res []DupGroup
+		for _, k := range keys {
+			if len(byDigest[k]) > 1 {
+				res = append(res, DupGroup{Size: size, Digest: []byte(k), Files: byDigest[k]})
+			}
+		}
+		return res
+	}
+
+	if size <= dopt.PartialSize {
+		return group(files, -1)
+	}
+	var groups []DupGroup
+	for _, g := range group(files, dopt.PartialSize) {
+		groups = append(groups, group(g.Files, -1)...)
+	}
+	return groups
+}
+
+// hashHead return digest of first limit bytes of path. limit < 0 is whole file.
+func hashHead(path string, h hash.Hash, limit int64) ([]byte, error) {
+	if limit < 0 {
+		return hashFile(path, h)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := copyBuffer(h, io.LimitReader(f, limit)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// replaceDups replace g.Files[1:] with links of g.Files[0].
+// Files changed after hashing are not replaced.
+func replaceDups(g *DupGroup, replace DupReplace) {
+	orig := g.Files[0]
+	for _, f := range g.Files[1:] {
+		err := replaceDup(orig, f, replace)
+		if err != nil {
+			if g.Err == nil {
+				g.Err = err
+			}
+			continue
+		}
+		g.Replaced = append(g.Replaced, f)
+	}
+}
+
+func replaceDup(orig, dup Info, replace DupReplace) error {
+	for _, f := range []Info{orig, dup} {
+		fi, err := os.Lstat(f.Path)
+		if err != nil {
+			return err
+		}
+		if fi.Size() != f.Fi.Size() || !fi.ModTime().Equal(f.Fi.ModTime()) {
+			return fmt.Errorf("[%s] is changed after hashing", f.Path)
+		}
+	}
+
+	// Temporary name is created exclusively, never remove existing file.
+	var (
+		tmp string
+		err error
+	)
+	for i := 0; ; i++ {
+		tmp = filepath.Join(filepath.Dir(dup.Path), fmt.Sprintf(".%s.dup%d", filepath.Base(dup.Path), i))
+		switch replace {
+		case DupHardlink:
+			err = os.Link(orig.Path, tmp)
+		case DupReflink:
+			err = reflinkFile(orig.Path, tmp)
+		}
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if replace == DupReflink {
+		err = os.Chmod(tmp, dup.Fi.Mode().Perm())
+		if err == nil {
+			err = os.Chtimes(tmp, dup.Fi.ModTime(), dup.Fi.ModTime())
+		}
+	}
+	if err == nil {
+		err = os.Rename(tmp, dup.Path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
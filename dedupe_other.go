@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package file
+
+import (
+	"os"
+)
+
+// fileID is not supported on this platform.
+func fileID(fi os.FileInfo) ([2]uint64, bool) {
+	return [2]uint64{}, false
+}
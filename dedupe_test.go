@@ -0,0 +1,106 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFindDuplicates is test FindDuplicates func across roots.
+func TestFindDuplicates(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	a, b := filepath.Join(tmp, "dir0"), filepath.Join(tmp, "dir1")
+	data := writeRandom(filepath.Join(a, "x"), 10000)
+	ioutil.WriteFile(filepath.Join(b, "y"), data, 0644)
+	os.Link(filepath.Join(a, "x"), filepath.Join(a, "x.link"))
+	// Same head and size, different tail.
+	other := append([]byte{}, data...)
+	other[len(other)-1]++
+	ioutil.WriteFile(filepath.Join(b, "z"), other, 0644)
+	ioutil.WriteFile(filepath.Join(a, "small"), []byte("hello"), 0644)
+	ioutil.WriteFile(filepath.Join(b, "small"), []byte("hello"), 0644)
+	ioutil.WriteFile(filepath.Join(b, "small2"), []byte("world"), 0644)
+
+	groups, err := FindDuplicates([]string{a, b}, Option{Recurse: true}, DupOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []DupGroup
+	for g := range groups {
+		if g.Err != nil {
+			t.Fatal(g.Err)
+		}
+		found = append(found, g)
+	}
+	if len(found) != 2 {
+		t.Fatalf("Expected: [%d] but actual: [%+v]\n", 2, found)
+	}
+	// Largest first, and hard link counted once.
+	if found[0].Size != 10000 || len(found[0].Files) != 2 || found[1].Size != 5 || len(found[1].Files) != 2 {
+		t.Fatalf("Expected: [%d] [%d] but actual: [%+v]\n", 10000, 5, found)
+	}
+	if len(found[0].Replaced) != 0 {
+		t.Fatal("Expected: report only")
+	}
+
+	// User file which has temporary name is not touched.
+	userFile := filepath.Join(b, ".y.dup0")
+	ioutil.WriteFile(userFile, []byte("user"), 0644)
+
+	groups, err = FindDuplicates([]string{a, b}, Option{Recurse: true}, DupOption{Replace: DupHardlink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for g := range groups {
+		if g.Err != nil {
+			t.Fatal(g.Err)
+		}
+		if len(g.Replaced) != len(g.Files)-1 {
+			t.Fatalf("Expected: [%d] but actual: [%d]\n", len(g.Files)-1, len(g.Replaced))
+		}
+	}
+	fx, _ := os.Stat(filepath.Join(a, "x"))
+	fy, _ := os.Stat(filepath.Join(b, "y"))
+	fz, _ := os.Stat(filepath.Join(b, "z"))
+	if !os.SameFile(fx, fy) || os.SameFile(fx, fz) {
+		t.Fatal("Expected: [x] and [y] are hard linked")
+	}
+	if b, _ := ioutil.ReadFile(userFile); string(b) != "user" {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", "user", b)
+	}
+
+	if _, err := FindDuplicates([]string{a}, Option{}, DupOption{Replace: "copy"}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
+
+// TestUnlinked is test unlinked func count hard links once.
+func TestUnlinked(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	var files []Info
+	for i := 0; i < 3; i++ {
+		p := filepath.Join(tmp, "dir2", string(rune('a'+i)))
+		if i == 0 {
+			ioutil.WriteFile(p, []byte("same"), 0644)
+		} else if err := os.Link(filepath.Join(tmp, "dir2", "a"), p); err != nil {
+			t.Skip("hard link is not supported:", err)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(tmp, "dir2", "d"), []byte("same"), 0644)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		p := filepath.Join(tmp, "dir2", name)
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, Info{Path: p, Fi: fi})
+	}
+	if res := unlinked(files); len(res) != 2 || res[1].Path != filepath.Join(tmp, "dir2", "d") {
+		t.Fatalf("Expected: [%d] but actual: [%+v]\n", 2, res)
+	}
+}
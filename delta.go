@@ -0,0 +1,220 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+)
+
+// deltaBlockSize is default block size of delta copy.
+const deltaBlockSize = 64 * 1024
+
+// deltaMaxLiteral is max pending literal bytes before write.
+const deltaMaxLiteral = 1024 * 1024
+
+// blockSig is signature of a block of dst.
+type blockSig struct {
+	index  int64
+	strong [sha256.Size]byte
+}
+
+// weakSum is rsync rolling checksum.
+type weakSum struct {
+	a, b uint32
+	n    uint32
+}
+
+func newWeakSum(p []byte) weakSum {
+	w := weakSum{n: uint32(len(p))}
+	for i, c := range p {
+		w.a += uint32(c)
+		w.b += uint32(len(p)-i) * uint32(c)
+	}
+	return w
+}
+
+// roll remove out and add in.
+func (w *weakSum) roll(out, in byte) {
+	w.a = w.a - uint32(out) + uint32(in)
+	w.b = w.b - w.n*uint32(out) + w.a
+}
+
+func (w weakSum) sum() uint32 {
+	return w.a&0xffff | w.b<<16
+}
+
+// signatures return block signatures of f indexed by weak sum.
+func signatures(f *os.File, bs int) (map[uint32][]blockSig, int64, error) {
+	sigs := map[uint32][]blockSig{}
+	buf := make([]byte, bs)
+	var size int64
+	for i := int64(0); ; i++ {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			w := newWeakSum(buf[:n]).sum()
+			sigs[w] = append(sigs[w], blockSig{index: i, strong: sha256.Sum256(buf[:n])})
+			size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sigs, size, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// deltaWriter apply delta to dst in place.
+type deltaWriter struct {
+	f       *os.File
+	bs      int
+	size    int64
+	pos     int64
+	written int64
+	buf     []byte
+}
+
+// literal write p at current position.
+func (dw *deltaWriter) literal(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	_, err := dw.f.WriteAt(p, dw.pos)
+	dw.pos += int64(len(p))
+	dw.written += int64(len(p))
+	return err
+}
+
+// block write old block i at current position. Same offset is not written.
+func (dw *deltaWriter) block(i int64, n int) error {
+	off := i * int64(dw.bs)
+	if off != dw.pos {
+		_, err := dw.f.ReadAt(dw.buf[:n], off)
+		if err != nil {
+			return err
+		}
+		_, err = dw.f.WriteAt(dw.buf[:n], dw.pos)
+		if err != nil {
+			return err
+		}
+		dw.written += int64(n)
+	}
+	dw.pos += int64(n)
+	return nil
+}
+
+// match return old block index matching window. Only blocks not overwritten yet
+// (offset is at or after position of window) can be used in place.
+func (dw *deltaWriter) match(sigs map[uint32][]blockSig, w weakSum, window []byte, at int64) (int64, bool) {
+	cands, ok := sigs[w.sum()]
+	if !ok {
+		return 0, false
+	}
+	var strong [sha256.Size]byte
+	computed := false
+	for _, c := range cands {
+		off := c.index * int64(dw.bs)
+		if off < at || off+int64(len(window)) > dw.size {
+			continue
+		}
+		if !computed {
+			strong = sha256.Sum256(window)
+			computed = true
+		}
+		if bytes.Equal(strong[:], c.strong[:]) {
+			return c.index, true
+		}
+	}
+	return 0, false
+}
+
+// copyDelta update f in place to content of src using rolling checksums.
+// Return bytes of new content and bytes actually written.
+func copyDelta(f *os.File, src io.Reader, bs int) (int64, int64, error) {
+	if bs <= 0 {
+		bs = deltaBlockSize
+	}
+	sigs, size, err := signatures(f, bs)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	dw := &deltaWriter{f: f, bs: bs, size: size, buf: make([]byte, bs)}
+	br := bufio.NewReaderSize(src, 1024*1024)
+
+	// buf is pending literal and window buf[start:].
+	buf := make([]byte, 0, deltaMaxLiteral+bs)
+	start := 0
+	fill := func() error {
+		n, err := io.ReadFull(br, buf[len(buf):len(buf)+bs])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return io.EOF
+		}
+		return err
+	}
+
+	eof := fill()
+	if eof != nil && eof != io.EOF {
+		return 0, 0, eof
+	}
+	w := newWeakSum(buf[start:])
+	for eof == nil {
+		if i, ok := dw.match(sigs, w, buf[start:], dw.pos+int64(start)); ok {
+			if err := dw.literal(buf[:start]); err != nil {
+				return dw.pos, dw.written, err
+			}
+			if err := dw.block(i, bs); err != nil {
+				return dw.pos, dw.written, err
+			}
+			buf, start = buf[:0], 0
+			eof = fill()
+			if eof != nil && eof != io.EOF {
+				return dw.pos, dw.written, eof
+			}
+			w = newWeakSum(buf)
+			continue
+		}
+
+		// Roll one byte.
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			eof = err
+			break
+		}
+		if err != nil {
+			return dw.pos, dw.written, err
+		}
+		w.roll(buf[start], c)
+		buf = append(buf, c)
+		start++
+
+		// Flush literal.
+		if start >= deltaMaxLiteral {
+			if err := dw.literal(buf[:start]); err != nil {
+				return dw.pos, dw.written, err
+			}
+			buf = append(buf[:0], buf[start:]...)
+			start = 0
+		}
+	}
+
+	// Tail shorter than block may match last block.
+	if len(buf) > start {
+		if i, ok := dw.match(sigs, newWeakSum(buf[start:]), buf[start:], dw.pos+int64(start)); ok {
+			if err := dw.literal(buf[:start]); err != nil {
+				return dw.pos, dw.written, err
+			}
+			if err := dw.block(i, len(buf)-start); err != nil {
+				return dw.pos, dw.written, err
+			}
+			buf, start = buf[:0], 0
+		}
+	}
+	if err := dw.literal(buf); err != nil {
+		return dw.pos, dw.written, err
+	}
+	return dw.pos, dw.written, f.Truncate(dw.pos)
+}
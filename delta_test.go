@@ -0,0 +1,68 @@
+package file
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCopyDelta is test CopyWithOption func with Delta option.
+func TestCopyDelta(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	bs := 1024
+	src := filepath.Join(tmp, "file0")
+	dst := filepath.Join(tmp, "dir2", "file0")
+	b := writeRandom(src, 100*bs+10)
+
+	// New dst is copied normally.
+	r, err := CopyWithOption(src, dst, CopyOption{Delta: true, DeltaBlockSize: bs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Method == CopyDelta || r.Written != int64(len(b)) {
+		t.Fatalf("Expected: not [%v] [%d] but actual: [%v] [%d]\n", CopyDelta, len(b), r.Method, r.Written)
+	}
+
+	tests := []struct {
+		name   string
+		modify func([]byte) []byte
+		max    int64
+	}{
+		{"same", func(b []byte) []byte { return b }, 0},
+		{"overwrite", func(b []byte) []byte {
+			c := append([]byte{}, b...)
+			copy(c[50*bs+3:], []byte("changed"))
+			return c
+		}, int64(bs)},
+		{"append", func(b []byte) []byte { return append(append([]byte{}, b...), []byte("appended")...) }, int64(bs)},
+		{"truncate", func(b []byte) []byte { return append([]byte{}, b[:60*bs+5]...) }, int64(bs)},
+		// Shifted data is rewritten in place.
+		{"delete", func(b []byte) []byte { return append(append([]byte{}, b[:10*bs]...), b[11*bs+7:]...) }, int64(len(b))},
+		{"insert", func(b []byte) []byte {
+			return append(append(append([]byte{}, b[:90*bs]...), []byte("inserted")...), b[90*bs:]...)
+		}, int64(11 * bs)},
+	}
+	for _, tt := range tests {
+		// Reset dst.
+		ioutil.WriteFile(dst, b, os.ModePerm)
+		c := tt.modify(b)
+		ioutil.WriteFile(src, c, os.ModePerm)
+
+		r, err := CopyWithOption(src, dst, CopyOption{Overwrite: true, Delta: true, DeltaBlockSize: bs, Verify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Log(tt.name, r.N, r.Written)
+		a, _ := ioutil.ReadFile(dst)
+		if !bytes.Equal(a, c) {
+			t.Fatalf("%s: Expected: same content [%v]\n", tt.name, dst)
+		}
+		if r.Method != CopyDelta || r.N != int64(len(c)) || r.Written > tt.max {
+			t.Fatalf("%s: Expected: [%v] [%d] written under [%d] but actual: [%v] [%d] [%d]\n", tt.name, CopyDelta, len(c), tt.max, r.Method, r.N, r.Written)
+		}
+	}
+}
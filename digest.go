@@ -0,0 +1,56 @@
+package file
+
+import (
+	"hash"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// hashInfos compute digest of regular files from in with bounded workers, and send to returned channel.
+// Directories and archive entries are passed through without digest.
+func hashInfos(fsys FileSystem, in chan Info, opt Option) chan Info {
+	workers := opt.HashWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	q := make(chan Info, 20)
+	wg := new(sync.WaitGroup)
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for info := range in {
+				if info.Err == nil && info.Fi.Mode().IsRegular() && !(opt.Archive && inArchive(info.Path)) {
+					info.Digest, info.Err = hashFS(fsys, info.Path, opt.Hash())
+				}
+				q <- info
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(q)
+	}()
+	return q
+}
+
+// hashFS return digest of path in fsys.
+func hashFS(fsys FileSystem, path string, h hash.Hash) ([]byte, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := copyBuffer(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// inArchive is whether path is entry of archive file.
+func inArchive(path string) bool {
+	return strings.Contains(filepath.ToSlash(path), ARCHIVESEPARATOR)
+}
@@ -0,0 +1,127 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// TestGetFilesHash is test GetInfos func with Hash option.
+func TestGetFilesHash(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	path := filepath.Join(tmp, "dir1", "foo")
+	ioutil.WriteFile(path, []byte("foo"), 0644)
+	want := sha256.Sum256([]byte("foo"))
+	empty := sha256.Sum256(nil)
+
+	infos, err := GetInfos(tmp, Option{Recurse: true, Hash: sha256.New, HashWorkers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for info := range infos {
+		if info.Err != nil {
+			t.Fatal(info.Err)
+		}
+		cnt++
+		switch {
+		case info.Fi.IsDir():
+			if info.Digest != nil {
+				t.Fatalf("Expected: [nil] but actual: [%x] [%s]\n", info.Digest, info.Path)
+			}
+		case info.Path == path:
+			if !bytes.Equal(info.Digest, want[:]) {
+				t.Fatalf("Expected: [%x] but actual: [%x]\n", want, info.Digest)
+			}
+		default:
+			if !bytes.Equal(info.Digest, empty[:]) {
+				t.Fatalf("Expected: [%x] but actual: [%x] [%s]\n", empty, info.Digest, info.Path)
+			}
+		}
+	}
+	if c := getCnt(GetInfos, tmp, Option{Recurse: true}, t); cnt != c {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", c, cnt)
+	}
+}
+
+// TestGetFilesHashArchive is test archive entries are not hashed.
+func TestGetFilesHashArchive(t *testing.T) {
+	tmp := setupArchive(t)
+	defer shutdown(tmp)
+
+	infos, err := GetFiles(tmp, Option{Recurse: true, Archive: true, Hash: sha256.New})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for info := range infos {
+		if info.Err != nil {
+			t.Fatal(info.Err)
+		}
+		if inArchive(info.Path) != (info.Digest == nil) {
+			t.Fatalf("Expected: digest only for real files but actual: [%s] [%x]\n", info.Path, info.Digest)
+		}
+	}
+}
+
+// TestGetFilesHashError is test hash error is set to Info.Err.
+func TestGetFilesHashError(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	errFault := errors.New("fault")
+	fsys, err := NewFaultFS(nil, Fault{Match: `file1$`, Op: FaultOpen, Err: errFault})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := GetFiles(tmp, Option{Recurse: true, FS: fsys, Hash: sha256.New})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := 0
+	for info := range infos {
+		if info.Err != nil {
+			errs++
+		}
+	}
+	if errs != 2 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 2, errs)
+	}
+}
+
+// TestHashCleared is test APIs not using digests never hash files.
+func TestHashCleared(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	var hashed int64
+	noHash := func() hash.Hash {
+		atomic.AddInt64(&hashed, 1)
+		return sha256.New()
+	}
+	opt := Option{Recurse: true, Hash: noHash}
+	if _, err := RemoveMatching(tmp, Option{Recurse: true, Hash: noHash, Matches: []string{`nothing$`}}, true); err != nil {
+		t.Fatal(err)
+	}
+	groups, err := FindDuplicates([]string{tmp}, opt, DupOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range groups {
+	}
+	mis, err := Mirror(filepath.Join(tmp, "dir0"), filepath.Join(tmp, "dir2"), opt, MirrorOption{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range mis {
+	}
+	if hashed != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 0, hashed)
+	}
+}
@@ -0,0 +1,160 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// FaultStat is Fault.Op for Stat.
+	FaultStat = "stat"
+	// FaultReadDir is Fault.Op for ReadDir.
+	FaultReadDir = "readdir"
+	// FaultOpen is Fault.Op for Open and Read.
+	FaultOpen = "open"
+)
+
+// Fault is fault injection rule of FaultFS.
+type Fault struct {
+	// Match is regexp matched against path.
+	Match string
+	// Op is target operation. Empty is all operations.
+	Op string
+	// Err is injected error. nil is no error (latency only).
+	Err error
+	// Latency is sleep time before operation.
+	Latency time.Duration
+	// Partial is number of entries (ReadDir) or bytes (Read) returned before Err.
+	// 0 is fail immediately.
+	Partial int
+	// Count is number of times to inject. 0 is unlimited.
+	Count int
+
+	re  *regexp.Regexp
+	hit int
+}
+
+// FaultFS is FileSystem wrapper that inject errors, latencies and partial reads.
+type FaultFS struct {
+	FS     FileSystem
+	Faults []*Fault
+
+	mu sync.Mutex
+}
+
+// NewFaultFS return FaultFS wrapping fs.
+func NewFaultFS(fs FileSystem, faults ...Fault) (*FaultFS, error) {
+	if fs == nil {
+		fs = OsFS{}
+	}
+	ffs := &FaultFS{FS: fs}
+	for _, f := range faults {
+		f := f
+		switch f.Op {
+		case "", FaultStat, FaultReadDir, FaultOpen:
+		default:
+			return nil, fmt.Errorf("Fault.Op: [%v] is not support", f.Op)
+		}
+		re, err := regexp.Compile(f.Match)
+		if err != nil {
+			return nil, err
+		}
+		f.re = re
+		ffs.Faults = append(ffs.Faults, &f)
+	}
+	return ffs, nil
+}
+
+// fault return first Fault matched op and name, and count it.
+func (ffs *FaultFS) fault(op, name string) *Fault {
+	ffs.mu.Lock()
+	defer ffs.mu.Unlock()
+	for _, f := range ffs.Faults {
+		if f.Op != "" && f.Op != op {
+			continue
+		}
+		if !f.re.MatchString(name) {
+			continue
+		}
+		if f.Count != 0 && f.hit >= f.Count {
+			continue
+		}
+		f.hit++
+		return f
+	}
+	return nil
+}
+
+// Stat is Stat with fault injection.
+func (ffs *FaultFS) Stat(name string) (os.FileInfo, error) {
+	f := ffs.fault(FaultStat, name)
+	if f != nil {
+		time.Sleep(f.Latency)
+		if f.Err != nil {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: f.Err}
+		}
+	}
+	return ffs.FS.Stat(name)
+}
+
+// ReadDir is ReadDir with fault injection.
+func (ffs *FaultFS) ReadDir(name string) ([]os.FileInfo, error) {
+	f := ffs.fault(FaultReadDir, name)
+	if f == nil {
+		return ffs.FS.ReadDir(name)
+	}
+	time.Sleep(f.Latency)
+	fis, err := ffs.FS.ReadDir(name)
+	if err != nil || f.Err == nil {
+		return fis, err
+	}
+	if f.Partial < len(fis) {
+		fis = fis[:f.Partial]
+	}
+	return fis, &os.PathError{Op: "readdirent", Path: name, Err: f.Err}
+}
+
+// Open is Open with fault injection.
+func (ffs *FaultFS) Open(name string) (io.ReadCloser, error) {
+	f := ffs.fault(FaultOpen, name)
+	if f == nil {
+		return ffs.FS.Open(name)
+	}
+	time.Sleep(f.Latency)
+	if f.Err != nil && f.Partial == 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: f.Err}
+	}
+	rc, err := ffs.FS.Open(name)
+	if err != nil || f.Err == nil {
+		return rc, err
+	}
+	return &faultReader{rc: rc, name: name, left: int64(f.Partial), err: f.Err}, nil
+}
+
+// faultReader return err after left bytes read.
+type faultReader struct {
+	rc   io.ReadCloser
+	name string
+	left int64
+	err  error
+}
+
+func (fr *faultReader) Read(p []byte) (int, error) {
+	if fr.left <= 0 {
+		return 0, &os.PathError{Op: "read", Path: fr.name, Err: fr.err}
+	}
+	if int64(len(p)) > fr.left {
+		p = p[:fr.left]
+	}
+	n, err := fr.rc.Read(p)
+	fr.left -= int64(n)
+	return n, err
+}
+
+func (fr *faultReader) Close() error {
+	return fr.rc.Close()
+}
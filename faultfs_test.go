@@ -0,0 +1,198 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func pathRe(p string) string {
+	return "^" + regexp.QuoteMeta(p) + "$"
+}
+
+func collect(fn func(string, Option) (chan Info, error), root string, opt Option, t *testing.T) ([]Info, []Info) {
+	infos, err := fn(root, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var oks, errs []Info
+	for i := range infos {
+		if i.Err != nil {
+			t.Log(i.Path, i.Err)
+			errs = append(errs, i)
+			continue
+		}
+		oks = append(oks, i)
+	}
+	return oks, errs
+}
+
+// TestFaultFSPermission is test walkers with permission denied.
+func TestFaultFSPermission(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	bar := filepath.Join(tmp, "dir0", "bar")
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(bar), Op: FaultStat, Err: os.ErrPermission})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oks, errs := collect(GetInfos, tmp, Option{Recurse: true, FS: ffs}, t)
+	if len(errs) != 1 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 1, len(errs))
+	}
+	if errs[0].Path != bar || !os.IsPermission(errs[0].Err) {
+		t.Fatalf("Expected: [%v] permission error but actual: [%v] [%v]\n", bar, errs[0].Path, errs[0].Err)
+	}
+	// dir0/bar and dir0/bar/foo are lost.
+	exp := 16
+	if len(oks) != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, len(oks))
+	}
+}
+
+// TestFaultFSDirInfosPermission is test GetDirInfos with permission denied.
+func TestFaultFSDirInfosPermission(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	foo := filepath.Join(tmp, "dir0", "foo")
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(foo), Op: FaultReadDir, Err: os.ErrPermission})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dis, err := GetDirInfos(tmp, Option{Recurse: true, FS: ffs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]error{}
+	for di := range dis {
+		found[di.Path] = di.Err
+	}
+	for _, p := range []string{foo, filepath.Join(tmp, "dir0"), tmp} {
+		if e, ok := found[p]; !ok || !os.IsPermission(e) {
+			t.Fatalf("Expected: [%v] permission error but actual: [%v]\n", p, e)
+		}
+	}
+	if e := found[filepath.Join(tmp, "dir1")]; e != nil {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", nil, e)
+	}
+}
+
+// TestFaultFSVanished is test walkers with directory removed during walk.
+func TestFaultFSVanished(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	dir1 := filepath.Join(tmp, "dir1")
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(dir1), Op: FaultStat, Err: os.ErrNotExist})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oks, errs := collect(GetFiles, tmp, Option{Recurse: true, FS: ffs}, t)
+	if len(errs) != 1 || !os.IsNotExist(errs[0].Err) {
+		t.Fatalf("Expected: [%v] not exist error but actual: [%v]\n", dir1, errs)
+	}
+	exp := 8
+	if len(oks) != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, len(oks))
+	}
+
+	// Root vanished before walk.
+	ffs, err = NewFaultFS(nil, Fault{Match: pathRe(tmp), Op: FaultStat, Err: os.ErrNotExist})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetFiles(tmp, Option{FS: ffs}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
+
+// TestFaultFSPartialReadDir is test walkers with ReadDir failed halfway.
+func TestFaultFSPartialReadDir(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	dir1 := filepath.Join(tmp, "dir1")
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(dir1), Op: FaultReadDir, Err: os.ErrClosed, Partial: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fis, err := ffs.ReadDir(dir1)
+	if err == nil || len(fis) != 1 {
+		t.Fatalf("Expected: [%d] entries and error but actual: [%d] [%v]\n", 1, len(fis), err)
+	}
+
+	_, errs := collect(GetInfos, tmp, Option{Recurse: true, FS: ffs}, t)
+	if len(errs) != 1 || errs[0].Path != dir1 {
+		t.Fatalf("Expected: [%v] error but actual: [%v]\n", dir1, errs)
+	}
+}
+
+// TestFaultFSSlow is test walkers with slow directory.
+func TestFaultFSSlow(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	latency := 100 * time.Millisecond
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(filepath.Join(tmp, "dir2")), Op: FaultReadDir, Latency: latency})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	oks, errs := collect(GetInfos, tmp, Option{Recurse: true, FS: ffs}, t)
+	if time.Since(start) < latency {
+		t.Fatalf("Expected: over [%v] but actual: [%v]\n", latency, time.Since(start))
+	}
+	exp := 18
+	if len(oks) != exp || len(errs) != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%d] errors: [%v]\n", exp, len(oks), errs)
+	}
+}
+
+// TestFaultFSOpen is test FaultFS Open with partial read and Count.
+func TestFaultFSOpen(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	f0 := filepath.Join(tmp, "file0")
+	ioutil.WriteFile(f0, []byte("test data"), os.ModePerm)
+
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(f0), Op: FaultOpen, Err: os.ErrClosed, Partial: 4, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := ffs.Open(f0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err == nil || string(b) != "test" {
+		t.Fatalf("Expected: [%v] and error but actual: [%v] [%v]\n", "test", string(b), err)
+	}
+
+	// Count exhausted.
+	rc, err = ffs.Open(f0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(b) != "test data" {
+		t.Fatalf("Expected: [%v] but actual: [%v] [%v]\n", "test data", string(b), err)
+	}
+
+	if _, err := NewFaultFS(nil, Fault{Op: "write"}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
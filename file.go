@@ -2,8 +2,8 @@ package file
 
 import (
 	"fmt"
+	"hash"
 	"io"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -30,6 +30,14 @@ type Option struct {
 	Recurse bool
 	Depth   int
 	Times   []Time
+	FS      FileSystem
+	Archive bool
+	// Hash is hash constructor to attach digest of regular files to Info, such as sha256.New.
+	// HashWorkers bound concurrent hashing. 0 is number of CPUs.
+	Hash        func() hash.Hash
+	HashWorkers int
+	// Cache reuse unchanged directories in GetDirInfos. Call Cache.Save to persist.
+	Cache *ScanCache
 
 	matchRe  *regexp.Regexp
 	ignoreRe *regexp.Regexp
@@ -49,6 +57,8 @@ type Info struct {
 	Fi    os.FileInfo
 	Depth int
 	Err   error
+	// Digest is hash of file content if Option.Hash is set.
+	Digest []byte
 }
 
 // DirInfo is directory information struct.
@@ -199,8 +209,10 @@ func GetDirInfos(root string, opt Option) (chan DirInfo, error) {
 		sem = make(chan struct{}, runtime.NumCPU())
 	)
 
+	fsys := opt.fileSystem()
+
 	// Check exist.
-	if !IsExistDir(root) {
+	if fi, err := fsys.Stat(root); err != nil || !fi.IsDir() {
 		return nil, fmt.Errorf("[%s] is not a directory", root)
 	}
 
@@ -293,13 +305,13 @@ func GetDirInfos(root string, opt Option) (chan DirInfo, error) {
 			Depth: depth,
 		}
 		di := DirInfo{Info: i}
-		di.Fi, di.Err = os.Stat(p)
+		di.Fi, di.Err = fsys.Stat(p)
 		if di.Err != nil {
 			qInfo(di)
 			return di
 		}
 
-		fis, err := ioutil.ReadDir(p)
+		entries, err := readDirEntries(fsys, opt.Cache, p, di.Fi)
 		depth++
 		if err != nil {
 			di.Err = err
@@ -307,34 +319,31 @@ func GetDirInfos(root string, opt Option) (chan DirInfo, error) {
 			return di
 		}
 
-		for _, fi := range fis {
-			if fi.IsDir() {
-				di.DirCount++
-				if (i.Depth < opt.Depth) || opt.Recurse {
-					path := filepath.Join(p, fi.Name())
-					select {
-					case sem <- struct{}{}:
-						// Async.
-						wg.Add(1)
-						go func(path string, depth int) {
-							defer wg.Done()
-							fromChild <- fn(path, depth)
-							<-sem
-						}(path, depth)
-					default:
-						// Sync.
-						d := fn(path, depth)
-						if d.Err != nil {
-							di.Err = d.Err
-						}
-						di.DirSize += d.DirSize
-						di.DirCount += d.DirCount
-						di.FileCount += d.FileCount
+		di.FileCount += entries.Files
+		di.DirSize += entries.Size
+		for _, name := range entries.Dirs {
+			di.DirCount++
+			if (i.Depth < opt.Depth) || opt.Recurse {
+				path := filepath.Join(p, name)
+				select {
+				case sem <- struct{}{}:
+					// Async.
+					wg.Add(1)
+					go func(path string, depth int) {
+						defer wg.Done()
+						fromChild <- fn(path, depth)
+						<-sem
+					}(path, depth)
+				default:
+					// Sync.
+					d := fn(path, depth)
+					if d.Err != nil {
+						di.Err = d.Err
 					}
+					di.DirSize += d.DirSize
+					di.DirCount += d.DirCount
+					di.FileCount += d.FileCount
 				}
-			} else {
-				di.FileCount++
-				di.DirSize += fi.Size()
 			}
 		}
 
@@ -406,8 +415,10 @@ func getInfo(root string, opt Option) (chan Info, error) {
 		sem = make(chan struct{}, runtime.NumCPU())
 	)
 
+	fsys := opt.fileSystem()
+
 	// Check exist.
-	if !IsExist(root) {
+	if _, err := fsys.Stat(root); err != nil {
 		return nil, fmt.Errorf("[%s] is not found", root)
 	}
 
@@ -484,6 +495,23 @@ func getInfo(root string, opt Option) (chan Info, error) {
 		}
 	}
 
+	// archive send archive entries as virtual directory.
+	archive := func(i Info) {
+		infos, err := archiveInfos(fsys, i.Path, i.Fi, i.Depth+1)
+		if err != nil {
+			i.Err = err
+			qInfo(i)
+			return
+		}
+		for _, ai := range infos {
+			// Check parent is descended.
+			if ai.Depth > i.Depth+1 && !((ai.Depth-1 < opt.Depth) || opt.Recurse) {
+				continue
+			}
+			qInfo(ai)
+		}
+	}
+
 	fn = func(p string, depth int) {
 
 		// Send p.
@@ -491,7 +519,7 @@ func getInfo(root string, opt Option) (chan Info, error) {
 			Path:  p,
 			Depth: depth,
 		}
-		i.Fi, i.Err = os.Stat(p)
+		i.Fi, i.Err = fsys.Stat(p)
 		qInfo(i)
 		if i.Err != nil {
 			return
@@ -499,10 +527,13 @@ func getInfo(root string, opt Option) (chan Info, error) {
 
 		// File pattern.
 		if !i.Fi.IsDir() {
+			if opt.Archive && IsArchive(p) {
+				archive(i)
+			}
 			return
 		}
 
-		fis, err := ioutil.ReadDir(p)
+		fis, err := fsys.ReadDir(p)
 		depth++
 		if err != nil {
 			i.Err = err
@@ -516,7 +547,7 @@ func getInfo(root string, opt Option) (chan Info, error) {
 				Fi:    fi,
 				Depth: depth,
 			}
-			if fi.IsDir() {
+			if fi.IsDir() || (opt.Archive && IsArchive(i.Path)) {
 				if (i.Depth < opt.Depth) || opt.Recurse {
 					select {
 					case sem <- struct{}{}:
@@ -547,6 +578,9 @@ func getInfo(root string, opt Option) (chan Info, error) {
 		close(q)
 	}()
 
+	if opt.Hash != nil {
+		return hashInfos(fsys, q, opt), err
+	}
 	return q, err
 }
 
@@ -624,47 +658,33 @@ func OsCopy(src, dst string) (*core.Cmd, error) {
 	return &cmd, nil
 }
 
-// Copy is file copy using io.Copy.
-func Copy(src, dst string, overwrite bool) (int64, error) {
+// OsCopyNative is pure Go replacement of OsCopy.
+// Mode, owner and times are preserved like `cp -p`, and verbose line same as
+// `cp -v` or `copy` is written to w. w nil is no output.
+func OsCopyNative(src, dst string, w io.Writer) error {
 
 	src = filepath.FromSlash(src)
 	dst = filepath.FromSlash(dst)
-	fs, err := os.Open(src)
-	if err != nil {
-		return -1, err
-	}
-	defer fs.Close()
-	fss, err := fs.Stat()
-	if err != nil {
-		return -1, err
-	}
-
-	if IsExist(dst) && !overwrite {
-		fds, err := os.Stat(dst)
-		if err != nil {
-			return -1, err
-		}
-
-		if fss.Size() == fds.Size() && fss.ModTime() == fds.ModTime() {
-			return 0, nil
-		}
-	}
 
-	ds, err := os.Create(dst)
-	if err != nil {
-		return -1, err
+	// Copy into directory.
+	if IsExistDir(dst) {
+		dst = filepath.Join(dst, filepath.Base(src))
 	}
-	defer ds.Close()
 
-	n, err := io.Copy(ds, fs)
+	_, err := CopyWithOption(src, dst, CopyOption{
+		Overwrite: true,
+		Preserve:  PreserveMode | PreserveTimes | PreserveAtime | PreserveOwner,
+	})
 	if err != nil {
-		return -1, err
+		return err
 	}
 
-	err = os.Chtimes(dst, fss.ModTime(), fss.ModTime())
-	if err != nil {
-		return -1, err
+	if w != nil {
+		if runtime.GOOS == "windows" {
+			fmt.Fprintln(w, "        1 file(s) copied.")
+		} else {
+			fmt.Fprintf(w, "'%s' -> '%s'\n", src, dst)
+		}
 	}
-
-	return n, nil
+	return nil
 }
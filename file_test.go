@@ -1,10 +1,12 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -569,6 +571,46 @@ func TestGetTime(t *testing.T) {
 	}
 }
 
+// TestOsCopyNative is test OsCopyNative func.
+func TestOsCopyNative(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir0", "file1")
+	ioutil.WriteFile(src, []byte{'t', 'e', 's', 't'}, 0640)
+	os.Chmod(src, 0640)
+	old := time.Now().Add(-3 * 24 * time.Hour).Truncate(time.Second)
+	os.Chtimes(src, old, old)
+
+	out := new(bytes.Buffer)
+
+	// Copy into directory.
+	dir2 := filepath.Join(tmp, "dir2")
+	err := OsCopyNative(src, dir2, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir2, "file1")
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 || !fi.ModTime().Equal(old) {
+		t.Fatalf("Expected: [%v] [%v] but actual: [%v] [%v]\n", os.FileMode(0640), old, fi.Mode().Perm(), fi.ModTime())
+	}
+	if runtime.GOOS != "windows" {
+		exp := fmt.Sprintf("'%s' -> '%s'\n", src, dst)
+		if out.String() != exp {
+			t.Fatalf("Expected: [%v] but actual: [%v]\n", exp, out.String())
+		}
+	}
+
+	err = OsCopyNative(filepath.Join(tmp, "nothing"), dir2, out)
+	if err == nil {
+		t.Fatalf("Expected error but actual: [%v]\n", err)
+	}
+}
+
 // TestMain is entry point.
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
@@ -0,0 +1,47 @@
+package file
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// FileSystem is filesystem interface used by walkers.
+type FileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+	Open(name string) (io.ReadCloser, error)
+}
+
+// OsFS is FileSystem using os package.
+type OsFS struct{}
+
+// Stat is os.Stat.
+func (OsFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// ReadDir is ioutil.ReadDir.
+func (OsFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
+
+// Open is os.Open.
+func (OsFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (opt Option) fileSystem() FileSystem {
+	if opt.FS == nil {
+		return OsFS{}
+	}
+	return opt.FS
+}
+
+// realFiles return opt walking real files only, without digests.
+// It is for APIs which change files and never use Info.Digest.
+func (opt Option) realFiles() Option {
+	opt.Archive = false
+	opt.Hash = nil
+	return opt
+}
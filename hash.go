@@ -0,0 +1,24 @@
+package file
+
+import (
+	"crypto/sha256"
+	"hash"
+
+	"github.com/cespare/xxhash/v2"
+	"lukechampine.com/blake3"
+)
+
+// HashSHA256 return SHA-256 hash. It is default of CopyOption.Hash.
+func HashSHA256() hash.Hash {
+	return sha256.New()
+}
+
+// HashXXH64 return xxHash64 hash. It is fast but not cryptographic.
+func HashXXH64() hash.Hash {
+	return xxhash.New()
+}
+
+// HashBLAKE3 return BLAKE3 hash with 256 bit digest.
+func HashBLAKE3() hash.Hash {
+	return blake3.New(32, nil)
+}
@@ -0,0 +1,39 @@
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// setIOPriority set I/O priority of current thread and return func to restore it.
+// Caller must lock OS thread.
+func setIOPriority(class IOClass, level int) (func(), error) {
+	old, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, 0, 0)
+	if errno != 0 {
+		return func() {}, errno
+	}
+	prio := uintptr(class)<<ioprioClassShift | uintptr(level)
+	_, _, errno = syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, prio)
+	if errno != 0 {
+		return func() {}, errno
+	}
+	return func() {
+		syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, old)
+	}, nil
+}
+
+// getIOPriority return I/O priority of current thread.
+func getIOPriority() (IOClass, int, error) {
+	prio, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, 0, 0)
+	if errno != 0 {
+		return IOClassNone, 0, errno
+	}
+	return IOClass(prio >> ioprioClassShift), int(prio & (1<<ioprioClassShift - 1)), nil
+}
@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestIOPriority is test setIOPriority func and CopyWithOption func with IOClass option.
+func TestIOPriority(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	oc, ol, err := getIOPriority()
+	if err != nil {
+		t.Skip("ioprio is not supported:", err)
+	}
+	restore, err := setIOPriority(IOClassBestEffort, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, l, _ := getIOPriority()
+	if c != IOClassBestEffort || l != 7 {
+		t.Fatalf("Expected: [%v] [%v] but actual: [%v] [%v]\n", IOClassBestEffort, 7, c, l)
+	}
+	restore()
+	c, l, _ = getIOPriority()
+	if c != oc || l != ol {
+		t.Fatalf("Expected: [%v] [%v] but actual: [%v] [%v]\n", oc, ol, c, l)
+	}
+
+	_, err = CopyWithOption(filepath.Join(tmp, "file0"), filepath.Join(tmp, "dir2", "file0"), CopyOption{IOClass: IOClassIdle})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
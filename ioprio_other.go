@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package file
+
+// setIOPriority is not supported on this platform.
+func setIOPriority(class IOClass, level int) (func(), error) {
+	return func() {}, nil
+}
+
+// getIOPriority is not supported on this platform.
+func getIOPriority() (IOClass, int, error) {
+	return IOClassNone, 0, nil
+}
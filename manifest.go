@@ -0,0 +1,223 @@
+package file
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestFormat is format of manifest.
+type ManifestFormat string
+
+const (
+	// ManifestSum is sha256sum compatible format. It has only hash and path.
+	ManifestSum ManifestFormat = "sum"
+	// ManifestJSON is JSON format with size, mtime and mode.
+	ManifestJSON ManifestFormat = "json"
+)
+
+// ManifestEntry is entry of manifest. Path is slash separated and relative to root.
+type ManifestEntry struct {
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	Mode    os.FileMode `json:"mode"`
+	Hash    string      `json:"hash"`
+}
+
+// ManifestReport is report of VerifyManifest func.
+type ManifestReport struct {
+	// Missing is paths in manifest but not under root.
+	Missing []string
+	// Extra is paths under root but not in manifest.
+	Extra []string
+	// Modified is paths which size or hash differ.
+	Modified []string
+	// Errors is Info failed to walk or hash.
+	Errors []Info
+}
+
+// OK is whether no difference and no error.
+func (r ManifestReport) OK() bool {
+	return len(r.Missing)+len(r.Extra)+len(r.Modified)+len(r.Errors) == 0
+}
+
+// manifestEntries return entries of regular files under root sorted by path. opt.Hash nil is sha256.
+func manifestEntries(root string, opt Option) ([]ManifestEntry, []Info, error) {
+	if opt.Hash == nil {
+		opt.Hash = sha256.New
+	}
+	opt.Archive = false
+	files, err := GetFiles(root, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		entries []ManifestEntry
+		errs    []Info
+	)
+	for f := range files {
+		if f.Err != nil {
+			errs = append(errs, f)
+			continue
+		}
+		if !f.Fi.Mode().IsRegular() {
+			continue
+		}
+		rel, err := filepath.Rel(root, f.Path)
+		if err != nil {
+			f.Err = err
+			errs = append(errs, f)
+			continue
+		}
+		entries = append(entries, ManifestEntry{
+			Path:    filepath.ToSlash(rel),
+			Size:    f.Fi.Size(),
+			ModTime: f.Fi.ModTime(),
+			Mode:    f.Fi.Mode(),
+			Hash:    hex.EncodeToString(f.Digest),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, errs, nil
+}
+
+// WriteManifest write manifest of regular files under root matched opt to w.
+// opt.Hash nil is sha256. Walk or hash errors are returned after writing other entries.
+func WriteManifest(w io.Writer, root string, opt Option, format ManifestFormat) error {
+	entries, errs, err := manifestEntries(filepath.Clean(filepath.FromSlash(root)), opt)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case ManifestSum:
+		bw := bufio.NewWriter(w)
+		for _, e := range entries {
+			if strings.ContainsAny(e.Path, "\\\n") {
+				// Same as sha256sum, escaped line start with backslash.
+				fmt.Fprintf(bw, "\\%s  %s\n", e.Hash, escapeSum(e.Path))
+				continue
+			}
+			fmt.Fprintf(bw, "%s  %s\n", e.Hash, e.Path)
+		}
+		err = bw.Flush()
+	case ManifestJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(entries)
+	default:
+		err = fmt.Errorf("unknown manifest format [%s]", format)
+	}
+	if err != nil {
+		return err
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("[%s]: %v", errs[0].Path, errs[0].Err)
+	}
+	return nil
+}
+
+// ReadManifest read manifest from r. Entries of ManifestSum have only Path and Hash.
+func ReadManifest(r io.Reader, format ManifestFormat) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	switch format {
+	case ManifestSum:
+		sc := bufio.NewScanner(r)
+		for n := 1; sc.Scan(); n++ {
+			line := sc.Text()
+			if line == "" {
+				continue
+			}
+			escaped := strings.HasPrefix(line, "\\")
+			if escaped {
+				line = line[1:]
+			}
+			i := strings.IndexByte(line, ' ')
+			if i < 0 || i+1 >= len(line) || (line[i+1] != ' ' && line[i+1] != '*') {
+				return nil, fmt.Errorf("line %d: invalid format [%s]", n, sc.Text())
+			}
+			e := ManifestEntry{Hash: strings.ToLower(line[:i]), Path: line[i+2:]}
+			if escaped {
+				e.Path = unescapeSum(e.Path)
+			}
+			entries = append(entries, e)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	case ManifestJSON:
+		if err := json.NewDecoder(r).Decode(&entries); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown manifest format [%s]", format)
+	}
+	return entries, nil
+}
+
+// escapeSum escape backslash and newline as sha256sum.
+func escapeSum(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(s)
+}
+
+// unescapeSum is reverse of escapeSum.
+func unescapeSum(s string) string {
+	return strings.NewReplacer("\\\\", "\\", "\\n", "\n").Replace(s)
+}
+
+// VerifyManifest read manifest from r and compare with regular files under root matched opt.
+// Files are modified if size (JSON only) or hash differ. opt.Hash must be same as when written.
+func VerifyManifest(r io.Reader, root string, opt Option, format ManifestFormat) (ManifestReport, error) {
+	var report ManifestReport
+	want, err := ReadManifest(r, format)
+	if err != nil {
+		return report, err
+	}
+	root = filepath.Clean(filepath.FromSlash(root))
+	got, errs, err := manifestEntries(root, opt)
+	if err != nil {
+		return report, err
+	}
+	report.Errors = errs
+
+	actual := make(map[string]ManifestEntry, len(got))
+	for _, e := range got {
+		actual[e.Path] = e
+	}
+	seen := make(map[string]bool, len(want))
+	for _, e := range errs {
+		if rel, err := filepath.Rel(root, e.Path); err == nil {
+			seen[filepath.ToSlash(rel)] = true
+		}
+	}
+	for _, w := range want {
+		if seen[w.Path] {
+			continue
+		}
+		seen[w.Path] = true
+		a, ok := actual[w.Path]
+		if !ok {
+			report.Missing = append(report.Missing, w.Path)
+			continue
+		}
+		if a.Hash != w.Hash || (format == ManifestJSON && a.Size != w.Size) {
+			report.Modified = append(report.Modified, w.Path)
+		}
+	}
+	for _, e := range got {
+		if !seen[e.Path] {
+			report.Extra = append(report.Extra, e.Path)
+		}
+	}
+	return report, nil
+}
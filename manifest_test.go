@@ -0,0 +1,79 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestManifest is test WriteManifest and VerifyManifest func with each format.
+func TestManifest(t *testing.T) {
+	for _, format := range []ManifestFormat{ManifestSum, ManifestJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			tmp := setup()
+			defer shutdown(tmp)
+			ioutil.WriteFile(filepath.Join(tmp, "dir1", "foo"), []byte("foo"), 0644)
+
+			var buf bytes.Buffer
+			if err := WriteManifest(&buf, tmp, Option{Recurse: true}, format); err != nil {
+				t.Fatal(err)
+			}
+			manifest := buf.Bytes()
+
+			report, err := VerifyManifest(bytes.NewReader(manifest), tmp, Option{Recurse: true}, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.OK() {
+				t.Fatalf("Expected: OK but actual: [%+v]\n", report)
+			}
+
+			ioutil.WriteFile(filepath.Join(tmp, "dir1", "foo"), []byte("bar"), 0644)
+			os.Remove(filepath.Join(tmp, "dir0", "file2"))
+			ioutil.WriteFile(filepath.Join(tmp, "dir2", "new"), nil, 0644)
+			report, err = VerifyManifest(bytes.NewReader(manifest), tmp, Option{Recurse: true}, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ManifestReport{Missing: []string{"dir0/file2"}, Extra: []string{"dir2/new"}, Modified: []string{"dir1/foo"}}
+			if !reflect.DeepEqual(report, want) {
+				t.Fatalf("Expected: [%+v] but actual: [%+v]\n", want, report)
+			}
+		})
+	}
+}
+
+// TestManifestSum is test ManifestSum is compatible with sha256sum.
+func TestManifestSum(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+	ioutil.WriteFile(filepath.Join(tmp, "dir1", "foo"), []byte("foo"), 0644)
+
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, filepath.Join(tmp, "dir1"), Option{}, ManifestSum); err != nil {
+		t.Fatal(err)
+	}
+	line := fmt.Sprintf("%x  foo\n", sha256.Sum256([]byte("foo")))
+	if !strings.Contains(buf.String(), line) {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", line, buf.String())
+	}
+
+	// Binary mode and escaped line.
+	sum := fmt.Sprintf("%x *foo\n\\%x  a\\\\b\\nc\n", sha256.Sum256([]byte("foo")), sha256.Sum256(nil))
+	entries, err := ReadManifest(strings.NewReader(sum), ManifestSum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Path != "foo" || entries[1].Path != "a\\b\nc" {
+		t.Fatalf("Expected: [%s] [%s] but actual: [%+v]\n", "foo", "a\\b\nc", entries)
+	}
+	if _, err := ReadManifest(strings.NewReader("broken\n"), ManifestSum); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
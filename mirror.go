@@ -0,0 +1,235 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Action is action done by Mirror.
+type Action string
+
+const (
+	// ActionCreate is created in dst.
+	ActionCreate Action = "created"
+	// ActionUpdate is overwritten in dst.
+	ActionUpdate Action = "updated"
+	// ActionDelete is deleted from dst.
+	ActionDelete Action = "deleted"
+	// ActionSkip is up to date.
+	ActionSkip Action = "skipped"
+)
+
+// ErrDeleteSkipped is reported by Mirror when deletion is skipped because walking src failed.
+var ErrDeleteSkipped = errors.New("walking src failed, deletion is skipped")
+
+// MirrorOption is option of Mirror func.
+type MirrorOption struct {
+	// Copy is used to compare and copy files.
+	Copy CopyOption
+	// Delete remove entries in dst not exist in src. Only entries matched Option are removed.
+	// Like rsync, nothing is deleted if any error occurred while walking src.
+	Delete bool
+	// DryRun report actions without touching disk.
+	DryRun bool
+}
+
+// MirrorInfo is action log of each entry of Mirror.
+type MirrorInfo struct {
+	Src    string
+	Dst    string
+	Action Action
+	CopyResult
+	Err error
+}
+
+// Mirror sync dst with src like rsync.
+// New and changed files decided by mopt.Copy.Compare are copied, and extraneous
+// entries in dst are deleted if mopt.Delete.
+func Mirror(src, dst string, opt Option, mopt MirrorOption) (chan MirrorInfo, error) {
+	var (
+		mu      sync.Mutex
+		seen    = map[string]bool{}
+		dirs    = map[string]bool{}
+		walkErr bool
+
+		wg = new(sync.WaitGroup)
+		q  = make(chan MirrorInfo, 20)
+	)
+
+	src, dst, err := checkTree(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	opt = opt.realFiles()
+	infos, err := GetInfos(src, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mopt.DryRun {
+		if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+
+	copt := mopt.Copy
+	cmp := copt.compare()
+	// Already compared.
+	copt.Compare = CompareAlways
+
+	// mark remember rel and its parents exist in src.
+	mark := func(rel string, isDir bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[rel] = true
+		if isDir {
+			dirs[rel] = true
+		}
+		for d := filepath.Dir(rel); d != "."; d = filepath.Dir(d) {
+			seen[d] = true
+			dirs[d] = true
+		}
+	}
+
+	mirrorEntry := func(info Info) MirrorInfo {
+		mi := MirrorInfo{Src: info.Path}
+		rel, err := filepath.Rel(src, info.Path)
+		if err != nil {
+			mi.Err = err
+			return mi
+		}
+		mi.Dst = filepath.Join(dst, rel)
+		mark(rel, info.Fi.IsDir())
+
+		// Decide action.
+		dfi, err := os.Lstat(mi.Dst)
+		switch {
+		case os.IsNotExist(err):
+			mi.Action = ActionCreate
+		case err != nil:
+			mi.Err = err
+			return mi
+		case dfi.IsDir() != info.Fi.IsDir():
+			mi.Action = ActionUpdate
+		case info.Fi.IsDir():
+			mi.Action = ActionSkip
+		default:
+			skip, err := cmp(mi.Src, mi.Dst, info.Fi, dfi)
+			if err != nil {
+				mi.Err = err
+				return mi
+			}
+			mi.Action = ActionUpdate
+			if skip {
+				mi.Action = ActionSkip
+			}
+		}
+		if mopt.DryRun || mi.Action == ActionSkip {
+			return mi
+		}
+
+		// Type changed.
+		if mi.Action == ActionUpdate && dfi.IsDir() != info.Fi.IsDir() {
+			if mi.Err = os.RemoveAll(mi.Dst); mi.Err != nil {
+				return mi
+			}
+		}
+		if info.Fi.IsDir() {
+			mi.Err = os.MkdirAll(mi.Dst, os.ModePerm)
+			return mi
+		}
+		if mi.Err = os.MkdirAll(filepath.Dir(mi.Dst), os.ModePerm); mi.Err != nil {
+			return mi
+		}
+		mi.CopyResult, mi.Err = CopyWithOption(mi.Src, mi.Dst, copt)
+		return mi
+	}
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for info := range infos {
+				if info.Err != nil {
+					mu.Lock()
+					walkErr = true
+					mu.Unlock()
+					q <- MirrorInfo{Src: info.Path, Err: info.Err}
+					continue
+				}
+				// Root is not an entry.
+				if info.Path == src {
+					continue
+				}
+				q <- mirrorEntry(info)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		if mopt.Delete && walkErr {
+			// Missing entries in seen may be unreadable, not removed.
+			q <- MirrorInfo{Src: src, Dst: dst, Err: ErrDeleteSkipped}
+		} else if mopt.Delete && IsExistDir(dst) {
+			mirrorDelete(src, dst, opt, seen, mopt.DryRun, q)
+		}
+		if !mopt.DryRun && IsExistDir(dst) {
+			preserveDirs(src, dst, dirs, copt.Preserve, func(s, d string, err error) {
+				q <- MirrorInfo{Src: s, Dst: d, Err: err}
+			})
+		}
+		close(q)
+	}()
+
+	return q, nil
+}
+
+// mirrorDelete remove entries in dst matched opt and not in seen.
+// Extraneous directory is removed with its contents.
+func mirrorDelete(src, dst string, opt Option, seen map[string]bool, dryRun bool, q chan MirrorInfo) {
+	infos, err := GetInfos(dst, opt)
+	if err != nil {
+		q <- MirrorInfo{Dst: dst, Err: err}
+		return
+	}
+	var rels []string
+	for info := range infos {
+		if info.Err != nil {
+			q <- MirrorInfo{Dst: info.Path, Err: info.Err}
+			continue
+		}
+		rel, err := filepath.Rel(dst, info.Path)
+		if err != nil || rel == "." || seen[rel] {
+			continue
+		}
+		rels = append(rels, rel)
+	}
+
+	// Parent first, and skip entries under removed directory.
+	sort.Strings(rels)
+	removed := map[string]bool{}
+	for _, rel := range rels {
+		under := false
+		for d := filepath.Dir(rel); d != "."; d = filepath.Dir(d) {
+			if removed[d] {
+				under = true
+				break
+			}
+		}
+		if under {
+			continue
+		}
+		removed[rel] = true
+		mi := MirrorInfo{Src: filepath.Join(src, rel), Dst: filepath.Join(dst, rel), Action: ActionDelete}
+		if !dryRun {
+			mi.Err = os.RemoveAll(mi.Dst)
+		}
+		q <- mi
+	}
+}
@@ -0,0 +1,146 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mirrorActions(t *testing.T, src, dst string, opt Option, mopt MirrorOption) map[Action]int {
+	mis, err := Mirror(src, dst, opt, mopt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acts := map[Action]int{}
+	for mi := range mis {
+		t.Log(mi.Action, mi.Src, mi.Dst)
+		if mi.Err != nil {
+			t.Fatal(mi.Err)
+		}
+		acts[mi.Action]++
+	}
+	return acts
+}
+
+// TestMirror is test Mirror func.
+func TestMirror(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir0")
+	dst := filepath.Join(tmp, "dir2", "mirror")
+	opt := Option{Recurse: true}
+
+	// Dry run touch nothing.
+	acts := mirrorActions(t, src, dst, opt, MirrorOption{DryRun: true})
+	if acts[ActionCreate] != 8 || IsExist(dst) {
+		t.Fatalf("Expected: [%d] created and no [%v] but actual: [%v]\n", 8, dst, acts)
+	}
+
+	acts = mirrorActions(t, src, dst, opt, MirrorOption{})
+	if acts[ActionCreate] != 8 {
+		t.Fatalf("Expected: [%d] created but actual: [%v]\n", 8, acts)
+	}
+	if cnt, exp := getCnt(GetInfos, dst, opt, t), getCnt(GetInfos, src, opt, t); cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+
+	// Second run skip all.
+	acts = mirrorActions(t, src, dst, opt, MirrorOption{})
+	if acts[ActionSkip] != 8 {
+		t.Fatalf("Expected: [%d] skipped but actual: [%v]\n", 8, acts)
+	}
+
+	// Change, remove and add.
+	ioutil.WriteFile(filepath.Join(src, "file0"), []byte("changed"), os.ModePerm)
+	os.RemoveAll(filepath.Join(src, "foo"))
+	os.Remove(filepath.Join(src, "file2"))
+	os.Create(filepath.Join(src, "hoge", "new"))
+	os.Create(filepath.Join(dst, "extra"))
+
+	exp := map[Action]int{ActionCreate: 1, ActionUpdate: 1, ActionSkip: 4, ActionDelete: 3}
+	acts = mirrorActions(t, src, dst, opt, MirrorOption{Delete: true, DryRun: true})
+	for a, n := range exp {
+		if acts[a] != n {
+			t.Fatalf("Expected: [%v] but actual: [%v]\n", exp, acts)
+		}
+	}
+	if !IsExist(filepath.Join(dst, "extra")) {
+		t.Fatalf("Expected: [%v] is not deleted in dry run\n", filepath.Join(dst, "extra"))
+	}
+
+	acts = mirrorActions(t, src, dst, opt, MirrorOption{Delete: true})
+	for a, n := range exp {
+		if acts[a] != n {
+			t.Fatalf("Expected: [%v] but actual: [%v]\n", exp, acts)
+		}
+	}
+	if cnt, exp := getCnt(GetInfos, dst, opt, t), getCnt(GetInfos, src, opt, t); cnt != exp {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", exp, cnt)
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(dst, "file0"))
+	if string(b) != "changed" {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", "changed", string(b))
+	}
+}
+
+// TestMirrorDeleteFilter is test Mirror func with delete only matched entries.
+func TestMirrorDeleteFilter(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir1")
+	dst := filepath.Join(tmp, "dir0")
+
+	// Only foo and bar are mirrored, and dir0/file* are kept.
+	opt := Option{Recurse: true, Matches: []string{`foo$`, `bar$`}}
+	mirrorActions(t, src, dst, opt, MirrorOption{Delete: true})
+
+	for _, p := range []string{"file0", "file1", "file2", "hoge", "foo", "bar"} {
+		if !IsExist(filepath.Join(dst, p)) {
+			t.Fatalf("Expected: [%v] exist\n", filepath.Join(dst, p))
+		}
+	}
+	if !IsExistFile(filepath.Join(dst, "foo")) || !IsExistFile(filepath.Join(dst, "bar")) {
+		t.Fatalf("Expected: [%v] and [%v] are files\n", filepath.Join(dst, "foo"), filepath.Join(dst, "bar"))
+	}
+}
+
+// TestMirrorDeleteWalkError is test Mirror func never delete when walking src failed.
+func TestMirrorDeleteWalkError(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir0")
+	dst := filepath.Join(tmp, "dir2")
+	mirrorActions(t, src, dst, Option{Recurse: true}, MirrorOption{})
+	ioutil.WriteFile(filepath.Join(dst, "bar", "important"), []byte("important"), 0644)
+	ioutil.WriteFile(filepath.Join(dst, "extra"), []byte("extra"), 0644)
+
+	fsys, err := NewFaultFS(nil, Fault{Match: `dir0[\\/]bar$`, Op: FaultReadDir, Err: os.ErrPermission})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mis, err := Mirror(src, dst, Option{Recurse: true, FS: fsys}, MirrorOption{Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped := false
+	for mi := range mis {
+		if mi.Action == ActionDelete {
+			t.Fatalf("Expected: no deletion but actual: [%v]\n", mi.Dst)
+		}
+		if mi.Err == ErrDeleteSkipped {
+			skipped = true
+		}
+	}
+	if !skipped {
+		t.Fatalf("Expected: [%v]\n", ErrDeleteSkipped)
+	}
+	for _, p := range []string{filepath.Join("bar", "important"), "extra"} {
+		if !IsExist(filepath.Join(dst, p)) {
+			t.Fatalf("Expected: [%v] exist\n", filepath.Join(dst, p))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// CopyRename is moved by rename.
+const CopyRename CopyMethod = "rename"
+
+// rename is os.Rename. It is replaced by tests to simulate cross device.
+var rename = os.Rename
+
+// Move move src file to dst. It rename if possible, and otherwise copy with
+// all metadata and verification, then remove src.
+func Move(src, dst string) (CopyResult, error) {
+	src = filepath.FromSlash(src)
+	dst = filepath.FromSlash(dst)
+
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return CopyResult{}, err
+	}
+	if fi.IsDir() {
+		return CopyResult{}, fmt.Errorf("[%s] is a directory", src)
+	}
+
+	err = rename(src, dst)
+	if err == nil {
+		return CopyResult{N: fi.Size(), Method: CopyRename}, nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return CopyResult{}, err
+	}
+
+	// Cross device.
+	r, err := CopyWithOption(src, dst, CopyOption{
+		Overwrite: true,
+		Preserve:  PreserveAll,
+		Verify:    true,
+	})
+	if err != nil {
+		return r, err
+	}
+	return r, os.Remove(src)
+}
+
+// MoveTree move entries under src filtered by opt to dst keeping structure.
+// If opt has no filter and dst does not exist, src is renamed at once.
+// Source directories which became empty are removed.
+func MoveTree(src, dst string, opt Option) (chan CopyInfo, error) {
+	var (
+		mu   sync.Mutex
+		dirs = map[string]bool{}
+
+		q = make(chan CopyInfo, 20)
+	)
+
+	src, dst, err := checkTree(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	// Whole tree.
+	whole := opt.Recurse && opt.Depth == 0 && len(opt.Matches) == 0 && len(opt.Ignores) == 0 && len(opt.Times) == 0
+	if whole && !IsExist(dst) {
+		err := os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+		if err == nil {
+			err = rename(src, dst)
+		}
+		if err == nil {
+			go func() {
+				q <- CopyInfo{Src: src, Dst: dst, CopyResult: CopyResult{Method: CopyRename}}
+				close(q)
+			}()
+			return q, nil
+		}
+		if !errors.Is(err, syscall.EXDEV) {
+			return nil, err
+		}
+	}
+
+	opt = opt.realFiles()
+	infos, err := GetInfos(src, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for info := range infos {
+			ci := CopyInfo{Src: info.Path, Err: info.Err}
+			if ci.Err != nil {
+				q <- ci
+				continue
+			}
+			if info.Path == src {
+				continue
+			}
+			rel, err := filepath.Rel(src, info.Path)
+			if err != nil {
+				ci.Err = err
+				q <- ci
+				continue
+			}
+			ci.Dst = filepath.Join(dst, rel)
+			parent := filepath.Dir(rel)
+			if info.Fi.IsDir() {
+				parent = rel
+			}
+			mu.Lock()
+			for d := parent; d != "." && !dirs[d]; d = filepath.Dir(d) {
+				dirs[d] = true
+			}
+			mu.Unlock()
+			if ci.Err = os.MkdirAll(filepath.Join(dst, parent), os.ModePerm); ci.Err != nil || info.Fi.IsDir() {
+				if ci.Err != nil {
+					q <- ci
+				}
+				continue
+			}
+			ci.CopyResult, ci.Err = Move(ci.Src, ci.Dst)
+			q <- ci
+		}
+
+		// Restore directories metadata, and remove emptied source directories.
+		preserveDirs(src, dst, dirs, PreserveAll, func(s, d string, err error) {
+			q <- CopyInfo{Src: s, Dst: d, Err: err}
+		})
+		removeEmptyDirs(src, dirs)
+		close(q)
+	}()
+
+	return q, nil
+}
+
+// removeEmptyDirs remove dirs (relative paths) under root and root itself if empty, deepest first.
+func removeEmptyDirs(root string, dirs map[string]bool) {
+	rels := make([]string, 0, len(dirs)+1)
+	for d := range dirs {
+		rels = append(rels, d)
+	}
+	sortReverse(rels)
+	rels = append(rels, ".")
+	for _, rel := range rels {
+		// Not empty directory is not removed.
+		os.Remove(filepath.Join(root, rel))
+	}
+}
@@ -0,0 +1,124 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// crossDevice make rename fail with EXDEV during test.
+func crossDevice() func() {
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	return func() { rename = os.Rename }
+}
+
+// TestMove is test Move func.
+func TestMove(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "file0")
+	dst := filepath.Join(tmp, "dir2", "file0")
+	ioutil.WriteFile(src, []byte("test"), 0640)
+	os.Chmod(src, 0640)
+	old := time.Now().Add(-3 * 24 * time.Hour).Truncate(time.Second)
+	os.Chtimes(src, old, old)
+
+	r, err := Move(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Method != CopyRename || IsExist(src) || !IsExist(dst) {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", CopyRename, r.Method)
+	}
+
+	// Cross device.
+	defer crossDevice()()
+	r, err = Move(dst, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Method == CopyRename || r.Digest == nil || IsExist(dst) {
+		t.Fatalf("Expected: copied and verified but actual: [%+v]\n", r)
+	}
+	fi, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 || !fi.ModTime().Equal(old) {
+		t.Fatalf("Expected: [%v] [%v] but actual: [%v] [%v]\n", os.FileMode(0640), old, fi.Mode().Perm(), fi.ModTime())
+	}
+
+	if _, err := Move(filepath.Join(tmp, "dir0"), dst); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
+
+// TestMoveTree is test MoveTree func.
+func TestMoveTree(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir0")
+	opt := Option{Recurse: true}
+	exp := getCnt(GetInfos, src, opt, t)
+
+	// Rename at once.
+	dst := filepath.Join(tmp, "dir2", "moved")
+	cis, err := MoveTree(src, dst, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ci := range cis {
+		if ci.Err != nil || ci.Method != CopyRename {
+			t.Fatalf("Expected: [%v] but actual: [%+v]\n", CopyRename, ci)
+		}
+	}
+	if IsExist(src) || getCnt(GetInfos, dst, opt, t) != exp {
+		t.Fatalf("Expected: [%v] is moved to [%v]\n", src, dst)
+	}
+
+	// Cross device.
+	defer crossDevice()()
+	cis, err = MoveTree(dst, src, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ci := range cis {
+		if ci.Err != nil {
+			t.Fatal(ci.Err)
+		}
+	}
+	if IsExist(dst) || getCnt(GetInfos, src, opt, t) != exp {
+		t.Fatalf("Expected: [%v] is moved to [%v]\n", dst, src)
+	}
+}
+
+// TestMoveTreeFilter is test MoveTree func with filter option.
+func TestMoveTreeFilter(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir0")
+	dst := filepath.Join(tmp, "dir2")
+	cis, err := MoveTree(src, dst, Option{Recurse: true, Matches: []string{`file\d$`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ci := range cis {
+		if ci.Err != nil {
+			t.Fatal(ci.Err)
+		}
+	}
+	if cnt := getCnt(GetFiles, dst, Option{Recurse: true}, t); cnt != 3 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 3, cnt)
+	}
+	if cnt := getCnt(GetFiles, src, Option{Recurse: true}, t); cnt != 2 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 2, cnt)
+	}
+}
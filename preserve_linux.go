@@ -0,0 +1,113 @@
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	aclAccess  = "system.posix_acl_access"
+	aclDefault = "system.posix_acl_default"
+)
+
+// accessTime return access time of fi.
+func accessTime(fi os.FileInfo) time.Time {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Now()
+	}
+	return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+}
+
+// preserveSys copy owner, xattrs and ACLs of src to dst.
+func preserveSys(src, dst string, fi os.FileInfo, p Preserve) error {
+	if p&PreserveOwner != 0 {
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			err := os.Lchown(dst, int(st.Uid), int(st.Gid))
+			// Like `cp -p`, unprivileged user keep own uid and try only gid.
+			if isPerm(err) {
+				err = os.Lchown(dst, -1, int(st.Gid))
+			}
+			if err != nil && !isPerm(err) {
+				return err
+			}
+		}
+	}
+
+	if p&(PreserveXattr|PreserveACL) == 0 {
+		return nil
+	}
+	names, err := listXattr(src)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		isACL := name == aclAccess || name == aclDefault
+		if isACL && p&PreserveACL == 0 || !isACL && p&PreserveXattr == 0 {
+			continue
+		}
+		val, err := getXattr(src, name)
+		if err != nil {
+			return err
+		}
+		err = syscall.Setxattr(dst, name, val, 0)
+		if err == syscall.ENOTSUP || (err == syscall.EPERM && !strings.HasPrefix(name, "user.")) {
+			// Not supported by dst filesystem or privileged namespace.
+			continue
+		}
+		if err != nil {
+			return &os.PathError{Op: "setxattr", Path: dst, Err: err}
+		}
+	}
+	return nil
+}
+
+func isPerm(err error) bool {
+	return err != nil && os.IsPermission(err)
+}
+
+// listXattr return xattr names of path.
+func listXattr(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err == syscall.ENOTSUP {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "listxattr", Path: path, Err: err}
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, &os.PathError{Op: "listxattr", Path: path, Err: err}
+	}
+	var names []string
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) != 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+// getXattr return xattr value of path.
+func getXattr(path, name string) ([]byte, error) {
+	size, err := syscall.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Getxattr(path, name, buf)
+	if err != nil {
+		return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+	}
+	return buf[:size], nil
+}
@@ -0,0 +1,85 @@
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestCopyPreserve is test CopyWithOption func with Preserve option.
+func TestCopyPreserve(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "file0")
+	dst := filepath.Join(tmp, "dir2", "file0")
+	ioutil.WriteFile(src, []byte("test"), os.ModePerm)
+	atime := time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Second)
+	mtime := time.Now().Add(-5 * 24 * time.Hour).Truncate(time.Second)
+	os.Chtimes(src, atime, mtime)
+
+	xattr := true
+	if err := syscall.Setxattr(src, "user.test", []byte("value"), 0); err != nil {
+		t.Log("xattr is not supported:", err)
+		xattr = false
+	}
+	owner := os.Geteuid() == 0
+	if owner {
+		os.Lchown(src, 1234, 5678)
+	}
+	os.Chmod(src, 0750|os.ModeSetgid)
+
+	_, err := CopyWithOption(src, dst, CopyOption{Preserve: PreserveAll})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0750|os.ModeSetgid {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", 0750|os.ModeSetgid, fi.Mode())
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", mtime, fi.ModTime())
+	}
+	if a := accessTime(fi); !a.Equal(atime) {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", atime, a)
+	}
+	st := fi.Sys().(*syscall.Stat_t)
+	if owner && (st.Uid != 1234 || st.Gid != 5678) {
+		t.Fatalf("Expected: [%d:%d] but actual: [%d:%d]\n", 1234, 5678, st.Uid, st.Gid)
+	}
+	if xattr {
+		val, err := getXattr(dst, "user.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(val) != "value" {
+			t.Fatalf("Expected: [%v] but actual: [%v]\n", "value", string(val))
+		}
+	}
+
+	// Default does not preserve atime and xattrs.
+	os.Remove(dst)
+	_, err = CopyWithOption(src, dst, CopyOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, _ = os.Stat(dst)
+	if a := accessTime(fi); a.Equal(atime) {
+		t.Fatalf("Expected: not [%v] but actual: [%v]\n", atime, a)
+	}
+	if xattr {
+		if _, err := getXattr(dst, "user.test"); err == nil {
+			t.Fatal("Expected error but actual: [nil]")
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package file
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime return access time of fi. Not supported, return now.
+func accessTime(fi os.FileInfo) time.Time {
+	return time.Now()
+}
+
+// preserveSys is not supported on this platform.
+func preserveSys(src, dst string, fi os.FileInfo, p Preserve) error {
+	return nil
+}
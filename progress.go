@@ -0,0 +1,99 @@
+package file
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Progress is copy progress reported to CopyOption.Progress.
+type Progress struct {
+	// Path is src of file copying now.
+	Path  string
+	Bytes int64
+	Total int64
+	// Rate is average bytes per second.
+	Rate float64
+	ETA  time.Duration
+}
+
+// progressTracker aggregate copied bytes and report Progress at interval.
+type progressTracker struct {
+	fn       func(Progress)
+	interval time.Duration
+	total    int64
+
+	mu    sync.Mutex
+	done  int64
+	path  string
+	start time.Time
+	last  time.Time
+}
+
+func newProgressTracker(fn func(Progress), interval time.Duration, total int64) *progressTracker {
+	if interval == 0 {
+		interval = time.Second
+	}
+	now := time.Now()
+	return &progressTracker{fn: fn, interval: interval, total: total, start: now, last: now}
+}
+
+// add add n bytes copied of path, and report if interval elapsed.
+func (pt *progressTracker) add(path string, n int64) {
+	if pt == nil {
+		return
+	}
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.done += n
+	pt.path = path
+	now := time.Now()
+	if now.Sub(pt.last) < pt.interval {
+		return
+	}
+	pt.last = now
+	pt.report(now)
+}
+
+// finish report final progress.
+func (pt *progressTracker) finish() {
+	if pt == nil {
+		return
+	}
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.report(time.Now())
+}
+
+func (pt *progressTracker) report(now time.Time) {
+	p := Progress{Path: pt.path, Bytes: pt.done, Total: pt.total}
+	if elapsed := now.Sub(pt.start).Seconds(); elapsed > 0 {
+		p.Rate = float64(pt.done) / elapsed
+	}
+	if p.Rate > 0 && pt.total > pt.done {
+		p.ETA = time.Duration(float64(pt.total-pt.done) / p.Rate * float64(time.Second))
+	}
+	pt.fn(p)
+}
+
+// reader return io.Reader counting bytes of path. nil tracker return r.
+func (pt *progressTracker) reader(path string, r io.Reader) io.Reader {
+	if pt == nil {
+		return r
+	}
+	return &progressReader{r: r, pt: pt, path: path}
+}
+
+type progressReader struct {
+	r    io.Reader
+	pt   *progressTracker
+	path string
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.pt.add(pr.path, int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,76 @@
+package file
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCopyProgress is test CopyWithOption func with Progress option.
+func TestCopyProgress(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	size := 1024 * 1024
+	src := filepath.Join(tmp, "file0")
+	writeRandom(src, size)
+
+	l, err := NewLimiter(int64(size)*10, 64*1024, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ps []Progress
+	copt := CopyOption{
+		Progress:         func(p Progress) { ps = append(ps, p) },
+		ProgressInterval: time.Nanosecond,
+		Limiter:          l,
+	}
+	_, err = CopyWithOption(src, filepath.Join(tmp, "dir2", "file0"), copt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) < 2 {
+		t.Fatalf("Expected: over [%d] but actual: [%d]\n", 2, len(ps))
+	}
+	for i, p := range ps {
+		if p.Total != int64(size) || p.Path != src || (i > 0 && p.Bytes < ps[i-1].Bytes) {
+			t.Fatalf("Expected: [%d] [%v] but actual: [%+v]\n", size, src, p)
+		}
+	}
+	mid, last := ps[len(ps)/2], ps[len(ps)-1]
+	if mid.Rate <= 0 || (mid.Bytes < mid.Total && mid.ETA <= 0) {
+		t.Fatalf("Expected: rate and ETA but actual: [%+v]\n", mid)
+	}
+	if last.Bytes != int64(size) || last.ETA != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%+v]\n", size, last)
+	}
+}
+
+// TestCopyTreeProgress is test CopyTree func with Progress option.
+func TestCopyTreeProgress(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	src := filepath.Join(tmp, "dir0")
+	writeRandom(filepath.Join(src, "file0"), 1000)
+	writeRandom(filepath.Join(src, "foo", "bar"), 2000)
+
+	var ps []Progress
+	cis, err := CopyTree(src, filepath.Join(tmp, "dir2"), Option{Recurse: true}, CopyOption{
+		Progress: func(p Progress) { ps = append(ps, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ci := range cis {
+		if ci.Err != nil {
+			t.Fatal(ci.Err)
+		}
+	}
+	if len(ps) == 0 {
+		t.Fatal("Expected: progress but actual: none")
+	}
+	if last := ps[len(ps)-1]; last.Bytes != 3000 || last.Total != 3000 {
+		t.Fatalf("Expected: [%d] but actual: [%+v]\n", 3000, last)
+	}
+}
@@ -0,0 +1,146 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RemoveReport is report of RemoveMatching func.
+type RemoveReport struct {
+	DryRun bool
+	// Files is removed files.
+	Files []Info
+	// Dirs is directories removed because they became empty.
+	Dirs []string
+	// Bytes is total size of removed files.
+	Bytes int64
+	// Errors is Info failed to walk or remove.
+	Errors []Info
+}
+
+// RemoveMatching remove files under root matched opt, and directories which became empty.
+// root itself and paths resolved outside root are never removed.
+// If dryRun, report without removing.
+func RemoveMatching(root string, opt Option, dryRun bool) (RemoveReport, error) {
+	report := RemoveReport{DryRun: dryRun}
+
+	root = filepath.Clean(filepath.FromSlash(root))
+	if !IsExistDir(root) {
+		return report, fmt.Errorf("[%s] is not a directory", root)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return report, err
+	}
+
+	opt = opt.realFiles()
+	files, err := GetFiles(root, opt)
+	if err != nil {
+		return report, err
+	}
+
+	var candidates []Info
+	for f := range files {
+		if f.Err != nil {
+			report.Errors = append(report.Errors, f)
+			continue
+		}
+		candidates = append(candidates, f)
+	}
+	removeInfos(realRoot, root, candidates, &report)
+	return report, nil
+}
+
+// removeInfos remove files under root, and directories which became empty, recording to report.
+func removeInfos(realRoot, root string, files []Info, report *RemoveReport) {
+	gone := map[string]bool{}
+	parents := map[string]bool{}
+	for _, f := range files {
+		if err := checkUnder(realRoot, root, f.Path); err != nil {
+			f.Err = err
+			report.Errors = append(report.Errors, f)
+			continue
+		}
+		gone[f.Path] = true
+		report.Files = append(report.Files, f)
+		for d := filepath.Dir(f.Path); d != root && d != "." && !parents[d]; d = filepath.Dir(d) {
+			parents[d] = true
+		}
+	}
+
+	// Directories become empty, deepest first.
+	dirs := make([]string, 0, len(parents))
+	for d := range parents {
+		dirs = append(dirs, d)
+	}
+	sortReverse(dirs)
+	for _, d := range dirs {
+		fis, err := ioutil.ReadDir(d)
+		if err != nil {
+			report.Errors = append(report.Errors, Info{Path: d, Err: err})
+			continue
+		}
+		empty := true
+		for _, fi := range fis {
+			if !gone[filepath.Join(d, fi.Name())] {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			gone[d] = true
+			report.Dirs = append(report.Dirs, d)
+		}
+	}
+
+	for _, f := range report.Files {
+		report.Bytes += f.Fi.Size()
+	}
+	if report.DryRun {
+		return
+	}
+
+	// Remove files and then directories.
+	removed := report.Files[:0]
+	for _, f := range report.Files {
+		if err := os.Remove(f.Path); err != nil {
+			f.Err = err
+			report.Errors = append(report.Errors, f)
+			report.Bytes -= f.Fi.Size()
+			continue
+		}
+		removed = append(removed, f)
+	}
+	report.Files = removed
+	dirsRemoved := report.Dirs[:0]
+	for _, d := range report.Dirs {
+		if err := os.Remove(d); err != nil {
+			report.Errors = append(report.Errors, Info{Path: d, Err: err})
+			continue
+		}
+		dirsRemoved = append(dirsRemoved, d)
+	}
+	report.Dirs = dirsRemoved
+}
+
+// checkUnder check path is under root and not root, following symlinks of parent directories.
+func checkUnder(realRoot, root, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("[%s] is not under [%s]", path, root)
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	if dir != realRoot && !strings.HasPrefix(dir, realRoot+string(filepath.Separator)) {
+		return fmt.Errorf("[%s] is resolved outside [%s]", path, root)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRemoveMatching is test RemoveMatching func.
+func TestRemoveMatching(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	opt := Option{Recurse: true, Matches: []string{`foo$`}}
+
+	// Dry run.
+	report, err := RemoveMatching(tmp, opt, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// dir0/bar/foo and dir1/foo, and dir0/bar become empty.
+	if len(report.Files) != 2 || len(report.Dirs) != 1 || report.Dirs[0] != filepath.Join(tmp, "dir0", "bar") {
+		t.Fatalf("Expected: [%d] files [%d] dirs but actual: [%+v]\n", 2, 1, report)
+	}
+	if !IsExist(filepath.Join(tmp, "dir0", "bar")) {
+		t.Fatal("Expected: dry run remove nothing")
+	}
+
+	report, err = RemoveMatching(tmp, opt, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 2 || len(report.Dirs) != 1 || len(report.Errors) != 0 {
+		t.Fatalf("Expected: [%d] files [%d] dirs but actual: [%+v]\n", 2, 1, report)
+	}
+	if IsExist(filepath.Join(tmp, "dir0", "bar")) || IsExist(filepath.Join(tmp, "dir1", "foo")) {
+		t.Fatal("Expected: removed")
+	}
+	// Empty directory not touched by removal is kept.
+	if !IsExist(filepath.Join(tmp, "dir0", "hoge")) {
+		t.Fatalf("Expected: [%v] exist\n", filepath.Join(tmp, "dir0", "hoge"))
+	}
+}
+
+// TestRemoveMatchingTime is test RemoveMatching func with time option.
+func TestRemoveMatchingTime(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	opt := Option{
+		Recurse: true,
+		Times:   []Time{{Base: time.Now().Add(-2 * 24 * time.Hour), Ope: "lt"}},
+	}
+	report, err := RemoveMatching(tmp, opt, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 || report.Files[0].Path != filepath.Join(tmp, "dir0", "file1") {
+		t.Fatalf("Expected: [%v] but actual: [%+v]\n", filepath.Join(tmp, "dir0", "file1"), report)
+	}
+}
+
+// TestRemoveMatchingOutside is test RemoveMatching func never remove outside root.
+func TestRemoveMatchingOutside(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	// dir2/link -> dir1
+	root := filepath.Join(tmp, "dir2")
+	if err := os.Symlink(filepath.Join(tmp, "dir1"), filepath.Join(root, "link")); err != nil {
+		t.Skip("symlink is not supported:", err)
+	}
+
+	report, err := RemoveMatching(root, Option{Recurse: true}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only link itself is removed.
+	if len(report.Files) != 1 || len(report.Errors) != 0 || IsExist(filepath.Join(root, "link")) {
+		t.Fatalf("Expected: [%v] is removed but actual: [%+v]\n", filepath.Join(root, "link"), report)
+	}
+	if cnt := getCnt(GetFiles, filepath.Join(tmp, "dir1"), Option{}, t); cnt != 3 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 3, cnt)
+	}
+
+	if _, err := RemoveMatching(filepath.Join(tmp, "file0"), Option{}, false); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
@@ -0,0 +1,140 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// resumeCheckpoint is bytes between checkpoints of resumable copy.
+var resumeCheckpoint int64 = 16 * 1024 * 1024
+
+// resumeState is sidecar state of partial dst.
+type resumeState struct {
+	Src     string    `json:"src"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Offset  int64     `json:"offset"`
+	Digest  string    `json:"digest"`
+}
+
+// partPath return partial file path of dst.
+func partPath(dst string) string {
+	return filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".part")
+}
+
+// statePath return sidecar state file path of dst.
+func statePath(dst string) string {
+	return partPath(dst) + ".json"
+}
+
+// readState read sidecar state. Broken or missing state return zero state.
+func readState(path string) resumeState {
+	var st resumeState
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return st
+	}
+	if json.Unmarshal(b, &st) != nil {
+		return resumeState{}
+	}
+	return st
+}
+
+// writeState write sidecar state atomically.
+func writeState(path string, st resumeState) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// copyResume copy fs to partial file ds from last confirmed offset.
+// Prefix of ds is verified by hash in sidecar state, and restart from zero if not matched.
+// h is fed with whole content if not nil, and read is throttled and tracked by copt.
+func copyResume(ds *os.File, fs io.Reader, src string, fi os.FileInfo, h hash.Hash, copt CopyOption) (int64, int64, error) {
+	var (
+		dst   = ds.Name()
+		sfile = dst + ".json"
+		st    = readState(sfile)
+		ph    = sha256.New()
+		w     io.Writer
+	)
+	w = ph
+	if h != nil {
+		w = io.MultiWriter(ph, h)
+	}
+
+	// Verify prefix.
+	offset := int64(0)
+	if st.Src == src && st.Size == fi.Size() && st.ModTime.Equal(fi.ModTime()) && st.Offset > 0 {
+		_, err := io.CopyN(w, ds, st.Offset)
+		if err == nil && hex.EncodeToString(ph.Sum(nil)) == st.Digest {
+			offset = st.Offset
+		}
+	}
+	if offset == 0 {
+		ph.Reset()
+		if h != nil {
+			h.Reset()
+		}
+	}
+
+	// Skip src and dst to offset.
+	err := ds.Truncate(offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, err = ds.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, 0, err
+	}
+	if sk, ok := fs.(io.Seeker); ok {
+		_, err = sk.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(ioutil.Discard, fs, offset)
+	}
+	if err != nil {
+		return offset, offset, err
+	}
+
+	st = resumeState{Src: src, Size: fi.Size(), ModTime: fi.ModTime(), Offset: offset}
+	copt.tracker.add(src, offset)
+	fs = copt.tracker.reader(src, copt.Limiter.Reader(fs))
+	n := offset
+	for {
+		m, cerr := copyBuffer(io.MultiWriter(ds, w), io.LimitReader(fs, resumeCheckpoint))
+		n += m
+
+		// Checkpoint written data even if failed.
+		if m > 0 {
+			err = ds.Sync()
+			if err == nil {
+				st.Offset, st.Digest = n, hex.EncodeToString(ph.Sum(nil))
+				err = writeState(sfile, st)
+			}
+			if err != nil {
+				return n, offset, err
+			}
+		}
+		if cerr != nil {
+			return n, offset, cerr
+		}
+		if m < resumeCheckpoint {
+			break
+		}
+	}
+	return n, offset, nil
+}
@@ -0,0 +1,107 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCopyResume is test CopyWithOption func with Resume option.
+func TestCopyResume(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	defer func(c int64) { resumeCheckpoint = c }(resumeCheckpoint)
+	resumeCheckpoint = 1024
+
+	src := filepath.Join(tmp, "file0")
+	dst := filepath.Join(tmp, "dir2", "file0")
+	b := writeRandom(src, 10*1024+10)
+
+	// Interrupted at 5000 bytes.
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(src), Op: FaultOpen, Err: os.ErrClosed, Partial: 5000, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CopyWithOption(src, dst, CopyOption{Resume: true, FS: ffs}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+	if IsExist(dst) || !IsExist(partPath(dst)) || !IsExist(statePath(dst)) {
+		t.Fatalf("Expected: only [%v] and [%v] exist\n", partPath(dst), statePath(dst))
+	}
+
+	// Resume from last checkpoint.
+	r, err := CopyWithOption(src, dst, CopyOption{Resume: true, FS: ffs, Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Resumed != 5000 || r.N != int64(len(b)) {
+		t.Fatalf("Expected: resumed [%d] total [%d] but actual: [%d] [%d]\n", 5000, len(b), r.Resumed, r.N)
+	}
+	if sum := sha256.Sum256(b); !bytes.Equal(r.Digest, sum[:]) {
+		t.Fatalf("Expected: [%x] but actual: [%x]\n", sum, r.Digest)
+	}
+	a, _ := ioutil.ReadFile(dst)
+	if !bytes.Equal(a, b) {
+		t.Fatalf("Expected: same content [%v]\n", dst)
+	}
+	if IsExist(partPath(dst)) || IsExist(statePath(dst)) {
+		t.Fatalf("Expected: [%v] and [%v] are removed\n", partPath(dst), statePath(dst))
+	}
+}
+
+// TestCopyResumeCorrupt is test CopyWithOption func with Resume option and corrupted partial file.
+func TestCopyResumeCorrupt(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	defer func(c int64) { resumeCheckpoint = c }(resumeCheckpoint)
+	resumeCheckpoint = 1024
+
+	src := filepath.Join(tmp, "file0")
+	dst := filepath.Join(tmp, "dir2", "file0")
+	b := writeRandom(src, 4096)
+
+	ffs, err := NewFaultFS(nil, Fault{Match: pathRe(src), Op: FaultOpen, Err: os.ErrClosed, Partial: 3000, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CopyWithOption(src, dst, CopyOption{Resume: true, FS: ffs}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+
+	// Corrupt partial file.
+	f, err := os.OpenFile(partPath(dst), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte("broken"), 100)
+	f.Close()
+
+	r, err := CopyWithOption(src, dst, CopyOption{Resume: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Resumed != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 0, r.Resumed)
+	}
+	a, _ := ioutil.ReadFile(dst)
+	if !bytes.Equal(a, b) {
+		t.Fatalf("Expected: same content [%v]\n", dst)
+	}
+
+	// Broken state file.
+	ffs, _ = NewFaultFS(nil, Fault{Match: pathRe(src), Op: FaultOpen, Err: os.ErrClosed, Partial: 3000, Count: 1})
+	CopyWithOption(src, dst, CopyOption{Overwrite: true, Resume: true, FS: ffs})
+	ioutil.WriteFile(statePath(dst), []byte("{broken"), 0600)
+	r, err = CopyWithOption(src, dst, CopyOption{Overwrite: true, Resume: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Resumed != 0 || r.N != int64(len(b)) {
+		t.Fatalf("Expected: [%d] [%d] but actual: [%d] [%d]\n", 0, len(b), r.Resumed, r.N)
+	}
+}
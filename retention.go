@@ -0,0 +1,219 @@
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Retention is GFS (grandfather-father-son) style retention policy.
+// Files matched Group are grouped by its submatches, and each group is decided separately.
+// A file is kept if any rule keeps it.
+type Retention struct {
+	// Group is regexp matched to file name. Files not matched are never removed.
+	// Empty means all files are in one group.
+	Group string
+	// TimeRe is regexp to parse date from file name with first submatch and TimeLayout.
+	// Empty means modification time.
+	TimeRe     string
+	TimeLayout string
+
+	// Last keep newest N files.
+	Last int
+	// Hourly, Daily, Weekly, Monthly and Yearly keep newest file of last N hours, days, ISO weeks, months and years.
+	Hourly  int
+	Daily   int
+	Weekly  int
+	Monthly int
+	Yearly  int
+
+	groupRe *regexp.Regexp
+	timeRe  *regexp.Regexp
+}
+
+// RetentionReport is report of ApplyRetention func.
+type RetentionReport struct {
+	RemoveReport
+	// Keep is kept files.
+	Keep []Info
+}
+
+type retentionBucket struct {
+	keep int
+	key  func(t time.Time) string
+}
+
+func (r Retention) buckets() []retentionBucket {
+	return []retentionBucket{
+		{r.Hourly, func(t time.Time) string { return t.Format("2006-01-02T15") }},
+		{r.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{r.Weekly, func(t time.Time) string {
+			y, w := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", y, w)
+		}},
+		{r.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{r.Yearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+}
+
+func (r *Retention) compile() error {
+	if r.Last == 0 && r.Hourly == 0 && r.Daily == 0 && r.Weekly == 0 && r.Monthly == 0 && r.Yearly == 0 {
+		// Empty policy would remove all files.
+		return fmt.Errorf("Retention has no keep rule")
+	}
+	if r.Last < 0 || r.Hourly < 0 || r.Daily < 0 || r.Weekly < 0 || r.Monthly < 0 || r.Yearly < 0 {
+		return fmt.Errorf("Retention keep rule must not be negative: [%d %d %d %d %d %d]", r.Last, r.Hourly, r.Daily, r.Weekly, r.Monthly, r.Yearly)
+	}
+	var err error
+	if r.Group != "" && r.groupRe == nil {
+		if r.groupRe, err = regexp.Compile(r.Group); err != nil {
+			return err
+		}
+	}
+	if r.TimeRe != "" && r.timeRe == nil {
+		if r.timeRe, err = regexp.Compile(r.TimeRe); err != nil {
+			return err
+		}
+		if r.timeRe.NumSubexp() < 1 || r.TimeLayout == "" {
+			return fmt.Errorf("TimeRe [%s] needs submatch and TimeLayout", r.TimeRe)
+		}
+	}
+	return nil
+}
+
+// timeOf return timestamp of f.
+func (r Retention) timeOf(f Info) (time.Time, error) {
+	if r.timeRe == nil {
+		return f.Fi.ModTime(), nil
+	}
+	name := filepath.Base(f.Path)
+	m := r.timeRe.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("[%s] has no date matched [%s]", name, r.TimeRe)
+	}
+	return time.ParseInLocation(r.TimeLayout, m[1], time.Local)
+}
+
+// Plan decide files to keep and to remove. Files not matched Group are not returned.
+// Files which timestamp is not parsed are kept with Err.
+func (r Retention) Plan(files []Info) (keep, remove []Info, err error) {
+	if err := r.compile(); err != nil {
+		return nil, nil, err
+	}
+
+	type entry struct {
+		Info
+		t time.Time
+	}
+	groups := map[string][]entry{}
+	for _, f := range files {
+		key := ""
+		if r.groupRe != nil {
+			m := r.groupRe.FindStringSubmatch(filepath.Base(f.Path))
+			if m == nil {
+				continue
+			}
+			if len(m) > 1 {
+				m = m[1:]
+			}
+			key = strings.Join(m, "\x00")
+		}
+		t, err := r.timeOf(f)
+		if err != nil {
+			f.Err = err
+			keep = append(keep, f)
+			continue
+		}
+		groups[key] = append(groups[key], entry{f, t})
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		es := groups[k]
+		// Newest first.
+		sort.SliceStable(es, func(i, j int) bool {
+			if es[i].t.Equal(es[j].t) {
+				return es[i].Path > es[j].Path
+			}
+			return es[i].t.After(es[j].t)
+		})
+		kept := make([]bool, len(es))
+		for i := 0; i < r.Last && i < len(es); i++ {
+			kept[i] = true
+		}
+		for _, b := range r.buckets() {
+			seen := map[string]bool{}
+			for i, e := range es {
+				if len(seen) >= b.keep {
+					break
+				}
+				key := b.key(e.t)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				kept[i] = true
+			}
+		}
+		for i, e := range es {
+			if kept[i] {
+				keep = append(keep, e.Info)
+			} else {
+				remove = append(remove, e.Info)
+			}
+		}
+	}
+	return keep, remove, nil
+}
+
+// ApplyRetention apply policy to files under root matched opt, and remove files not kept.
+// Directories which became empty are removed as RemoveMatching. If dryRun, report without removing.
+func ApplyRetention(root string, opt Option, policy Retention, dryRun bool) (RetentionReport, error) {
+	report := RetentionReport{RemoveReport: RemoveReport{DryRun: dryRun}}
+
+	root = filepath.Clean(filepath.FromSlash(root))
+	if !IsExistDir(root) {
+		return report, fmt.Errorf("[%s] is not a directory", root)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return report, err
+	}
+	if err := policy.compile(); err != nil {
+		return report, err
+	}
+
+	opt = opt.realFiles()
+	files, err := GetFiles(root, opt)
+	if err != nil {
+		return report, err
+	}
+	var infos []Info
+	for f := range files {
+		if f.Err != nil {
+			report.Errors = append(report.Errors, f)
+			continue
+		}
+		infos = append(infos, f)
+	}
+
+	keep, remove, err := policy.Plan(infos)
+	if err != nil {
+		return report, err
+	}
+	for _, f := range keep {
+		if f.Err != nil {
+			report.Errors = append(report.Errors, f)
+		}
+	}
+	report.Keep = keep
+	removeInfos(realRoot, root, remove, &report.RemoveReport)
+	return report, nil
+}
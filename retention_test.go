@@ -0,0 +1,128 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestApplyRetention is test ApplyRetention func with date in file name.
+func TestApplyRetention(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// 2024-01-01 - 2024-03-31 of two groups.
+	for d := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local); d.Month() < 4; d = d.AddDate(0, 0, 1) {
+		for _, g := range []string{"db", "web"} {
+			ioutil.WriteFile(filepath.Join(tmp, g+"-"+d.Format("20060102")+".bak"), []byte(g), 0644)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(tmp, "db-latest.bak"), []byte("db"), 0644)
+	ioutil.WriteFile(filepath.Join(tmp, "notes.txt"), []byte("notes"), 0644)
+
+	policy := Retention{
+		Group:      `^(\w+)-.*\.bak$`,
+		TimeRe:     `-(\d{8})\.bak$`,
+		TimeLayout: "20060102",
+		Daily:      7,
+		Weekly:     4,
+		Monthly:    3,
+	}
+	report, err := ApplyRetention(tmp, Option{}, policy, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Daily 03-25..03-31, weekly 03-24, 03-17, 03-10, monthly 02-29, 01-31, and db-latest.bak.
+	if len(report.Keep) != 12*2+1 || len(report.Files) != (91-12)*2 || len(report.Errors) != 1 {
+		t.Fatalf("Expected: keep [%d] remove [%d] but actual: keep [%d] remove [%d] errors [%d]\n", 25, 158, len(report.Keep), len(report.Files), len(report.Errors))
+	}
+	if !IsExist(filepath.Join(tmp, "db-20240101.bak")) {
+		t.Fatal("Expected: dry run remove nothing")
+	}
+
+	if _, err := ApplyRetention(tmp, Option{}, policy, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"db-20240331.bak", "web-20240325.bak", "db-20240310.bak", "web-20240229.bak", "db-20240131.bak", "db-latest.bak", "notes.txt"} {
+		if !IsExist(filepath.Join(tmp, name)) {
+			t.Fatalf("Expected: [%s] kept\n", name)
+		}
+	}
+	for _, name := range []string{"web-20240323.bak", "db-20240228.bak", "web-20240101.bak"} {
+		if IsExist(filepath.Join(tmp, name)) {
+			t.Fatalf("Expected: [%s] removed\n", name)
+		}
+	}
+}
+
+// TestRetentionPlan is test Retention Plan func with modification time.
+func TestRetentionPlan(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	now := time.Now()
+	var files []Info
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(tmp, "app.log."+string(rune('0'+i)))
+		ioutil.WriteFile(path, []byte("log"), 0644)
+		mod := now.Add(-time.Duration(i) * time.Hour)
+		os.Chtimes(path, mod, mod)
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, Info{Path: path, Fi: fi})
+	}
+	keep, remove, err := Retention{Last: 2}.Plan(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keep) != 2 || len(remove) != 3 || keep[0].Path != files[0].Path || keep[1].Path != files[1].Path {
+		t.Fatalf("Expected: keep [%d] remove [%d] but actual: [%v] [%v]\n", 2, 3, keep, remove)
+	}
+
+	if _, _, err := (Retention{Last: 1, TimeRe: `(\d+)`}).Plan(files); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
+
+// TestRetentionEmpty is test Retention without keep rule is rejected.
+func TestRetentionEmpty(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	if _, _, err := (Retention{}).Plan(nil); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+	report, err := ApplyRetention(filepath.Join(tmp, "dir0"), Option{Recurse: true}, Retention{Group: `.*`}, false)
+	if err == nil || len(report.Files) != 0 {
+		t.Fatalf("Expected error but actual: [%v] [%+v]\n", err, report)
+	}
+	if !IsExist(filepath.Join(tmp, "dir0", "file0")) {
+		t.Fatal("Expected: nothing removed")
+	}
+}
+
+// TestRetentionNegative is test negative keep rule is rejected.
+func TestRetentionNegative(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	for _, r := range []Retention{{Last: -1}, {Daily: 3, Weekly: -1}, {Last: 1, Yearly: -2}} {
+		if _, _, err := r.Plan(nil); err == nil {
+			t.Fatalf("Expected error but actual: [nil] [%+v]\n", r)
+		}
+	}
+	report, err := ApplyRetention(filepath.Join(tmp, "dir0"), Option{Recurse: true}, Retention{Last: -1}, false)
+	if err == nil || len(report.Files) != 0 {
+		t.Fatalf("Expected error but actual: [%v] [%+v]\n", err, report)
+	}
+	if !IsExist(filepath.Join(tmp, "dir0", "file0")) {
+		t.Fatal("Expected: nothing removed")
+	}
+}
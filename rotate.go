@@ -0,0 +1,382 @@
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotateTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateOption is option of RotateWriter.
+type RotateOption struct {
+	// MaxSize rotate before file exceed MaxSize bytes. 0 is unlimited.
+	MaxSize int64
+	// Interval rotate when time cross Interval boundary aligned to UTC. 0 is never.
+	Interval time.Duration
+	// Compress archives with gzip.
+	Compress bool
+
+	// MaxFiles and MaxTotal cap archive count and total bytes. 0 is unlimited.
+	MaxFiles int
+	MaxTotal int64
+	// Retention is applied to archives before MaxFiles and MaxTotal. Group and TimeRe are set by RotateWriter.
+	Retention *Retention
+
+	// Clock nil is real time.
+	Clock Clock
+}
+
+// RotateWriter is io.WriteCloser which rotate file by size or time.
+// Archives are named like app-2006-01-02T15-04-05.000.log(.gz) in same directory.
+// Compression and cleanup of archives run in background, and their errors are
+// returned by next Rotate or Close instead of Write.
+type RotateWriter struct {
+	path string
+	opt  RotateOption
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+
+	// hk serialize background housekeeping, and wg wait it on Close.
+	hk    sync.Mutex
+	wg    sync.WaitGroup
+	errMu sync.Mutex
+	err   error
+}
+
+// NewRotateWriter open path to append, and return RotateWriter.
+func NewRotateWriter(path string, opt RotateOption) (*RotateWriter, error) {
+	if opt.Clock == nil {
+		opt.Clock = realClock{}
+	}
+	if opt.Retention != nil {
+		policy := *opt.Retention
+		if err := policy.compile(); err != nil {
+			return nil, err
+		}
+	}
+	w := &RotateWriter{path: filepath.Clean(filepath.FromSlash(path)), opt: opt}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = fi.Size()
+	w.opened = w.opt.Clock.Now()
+	// Existing file is started at its modification time.
+	if w.size > 0 && fi.ModTime().Before(w.opened) {
+		w.opened = fi.ModTime()
+	}
+	return nil
+}
+
+// Write write p to current file, rotating before if needed.
+// Failure of rotation does not drop p, it is written to current file and the error is returned by next Rotate or Close.
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+
+	now := w.opt.Clock.Now()
+	over := w.opt.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opt.MaxSize
+	expired := w.opt.Interval > 0 && !now.Truncate(w.opt.Interval).Equal(w.opened.Truncate(w.opt.Interval))
+	if over || expired {
+		if err := w.rotate(now); err != nil {
+			w.setErr(err)
+			if w.f == nil {
+				return 0, err
+			}
+		}
+	}
+
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate close current file, archive it and open new file.
+// It also return error of previous background housekeeping.
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	err := w.rotate(w.opt.Clock.Now())
+	if perr := w.takeErr(); err == nil {
+		err = perr
+	}
+	return err
+}
+
+// rotate archive current file and open new one. If archive failed, current file is reopened.
+func (w *RotateWriter) rotate(now time.Time) error {
+	cerr := w.f.Close()
+	w.f = nil
+
+	archive := w.archiveName(now)
+	if err := rename(w.path, archive); err != nil {
+		// Keep writing to current file.
+		if oerr := w.open(); oerr != nil {
+			return oerr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.hk.Lock()
+		defer w.hk.Unlock()
+		if w.opt.Compress {
+			if err := gzipFile(archive); err != nil && !os.IsNotExist(err) {
+				w.setErr(err)
+			}
+		}
+		if err := w.cleanup(); err != nil {
+			w.setErr(err)
+		}
+	}()
+	return cerr
+}
+
+// setErr record first error for next Rotate or Close.
+func (w *RotateWriter) setErr(err error) {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// takeErr return and clear recorded error.
+func (w *RotateWriter) takeErr() error {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// split return directory, prefix and extension of archive name.
+func (w *RotateWriter) split() (string, string, string) {
+	dir, name := filepath.Split(w.path)
+	ext := filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext) + "-", ext
+}
+
+// archiveName return unused archive path for now.
+// Collision suffix is next to largest existing one, so it keep newer order
+// even if older archive of same stamp was removed.
+func (w *RotateWriter) archiveName(now time.Time) string {
+	dir, prefix, ext := w.split()
+	stamp := now.Format(rotateTimeFormat)
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(prefix+stamp) + `(\.\d+)?` + regexp.QuoteMeta(ext) + `(\.gz)?$`)
+	last := 0
+	fis, _ := ioutil.ReadDir(dir)
+	for _, fi := range fis {
+		m := re.FindStringSubmatch(fi.Name())
+		if m == nil {
+			continue
+		}
+		n := 1
+		if m[1] != "" {
+			n, _ = strconv.Atoi(m[1][1:])
+		}
+		if n > last {
+			last = n
+		}
+	}
+	if last == 0 {
+		return filepath.Join(dir, prefix+stamp+ext)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s%s.%d%s", prefix, stamp, last+1, ext))
+}
+
+// Archives return archives of RotateWriter, newest first.
+func (w *RotateWriter) Archives() ([]Info, error) {
+	_, prefix, ext := w.split()
+	re := regexp.MustCompile(w.archivePattern(prefix, ext))
+	files, err := GetFiles(filepath.Dir(w.path), Option{})
+	if err != nil {
+		return nil, err
+	}
+	var archives []Info
+	for f := range files {
+		if f.Err != nil {
+			return nil, f.Err
+		}
+		if re.MatchString(filepath.Base(f.Path)) {
+			archives = append(archives, f)
+		}
+	}
+	sortArchives(archives, re)
+	return archives, nil
+}
+
+// sortArchives sort archives matched by re newest first.
+// Archives in same millisecond are ordered by collision suffix.
+func sortArchives(archives []Info, re *regexp.Regexp) {
+	type key struct {
+		t time.Time
+		n int
+	}
+	keys := make(map[string]key, len(archives))
+	for _, a := range archives {
+		var k key
+		m := re.FindStringSubmatch(filepath.Base(a.Path))
+		if m != nil {
+			k.t, _ = time.ParseInLocation(rotateTimeFormat, m[1], time.Local)
+			k.n = 1
+			if m[2] != "" {
+				k.n, _ = strconv.Atoi(m[2][1:])
+			}
+		}
+		keys[a.Path] = k
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		ki, kj := keys[archives[i].Path], keys[archives[j].Path]
+		if !ki.t.Equal(kj.t) {
+			return ki.t.After(kj.t)
+		}
+		return ki.n > kj.n
+	})
+}
+
+func (w *RotateWriter) archivePattern(prefix, ext string) string {
+	return `^` + regexp.QuoteMeta(prefix) + `(\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3})(\.\d+)?` + regexp.QuoteMeta(ext) + `(\.gz)?$`
+}
+
+// cleanup remove archives by Retention, MaxFiles and MaxTotal.
+func (w *RotateWriter) cleanup() error {
+	if w.opt.Retention == nil && w.opt.MaxFiles <= 0 && w.opt.MaxTotal <= 0 {
+		return nil
+	}
+	archives, err := w.Archives()
+	if err != nil {
+		return err
+	}
+
+	var remove []Info
+	if w.opt.Retention != nil {
+		_, prefix, ext := w.split()
+		policy := *w.opt.Retention
+		policy.Group = ""
+		policy.TimeRe = w.archivePattern(prefix, ext)
+		policy.TimeLayout = rotateTimeFormat
+		policy.groupRe, policy.timeRe = nil, nil
+		keep, rm, err := policy.Plan(archives)
+		if err != nil {
+			return err
+		}
+		archives, remove = keep, rm
+		sortArchives(archives, regexp.MustCompile(policy.TimeRe))
+	}
+
+	var total int64
+	for i, a := range archives {
+		total += a.Fi.Size()
+		if (w.opt.MaxFiles > 0 && i >= w.opt.MaxFiles) || (w.opt.MaxTotal > 0 && total > w.opt.MaxTotal) {
+			remove = append(remove, a)
+		}
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(w.path)
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	var report RemoveReport
+	removeInfos(realDir, dir, remove, &report)
+	if len(report.Errors) > 0 {
+		return report.Errors[0].Err
+	}
+	return nil
+}
+
+// Close close current file and wait background housekeeping.
+// It return error of closing or recorded error of rotation and housekeeping.
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.f != nil {
+		err = w.f.Close()
+		w.f = nil
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	if herr := w.takeErr(); err == nil {
+		err = herr
+	}
+	return err
+}
+
+// gzipFile compress path to path.gz and remove path.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
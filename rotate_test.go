@@ -0,0 +1,186 @@
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRotateWriter is test RotateWriter rotate by size and cap archives.
+func TestRotateWriter(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)}
+	path := filepath.Join(tmp, "log", "app.log")
+	w, err := NewRotateWriter(path, RotateOption{MaxSize: 10, Compress: true, MaxFiles: 3, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 6; i++ {
+		clock.Sleep(time.Second)
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archives, err := w.Archives()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5 rotated, capped to 3.
+	if len(archives) != 3 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 3, len(archives))
+	}
+	newest := filepath.Join(tmp, "log", "app-2024-01-01T00-00-06.000.log.gz")
+	if archives[0].Path != newest {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", newest, archives[0].Path)
+	}
+	f, err := os.Open(newest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(zr); string(b) != "0123456789" {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", "0123456789", b)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "0123456789" {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", "0123456789", b)
+	}
+}
+
+// TestRotateWriterCollision is test archives in same millisecond are capped oldest first.
+func TestRotateWriterCollision(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)}
+	path := filepath.Join(tmp, "app.log")
+	w, err := NewRotateWriter(path, RotateOption{MaxSize: 2, MaxFiles: 3, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Clock never advance, so all archives have same stamp.
+	for i := 0; i < 12; i++ {
+		if _, err := w.Write([]byte(fmt.Sprintf("%02d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archives, err := w.Archives()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 3 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 3, len(archives))
+	}
+	for i, a := range archives {
+		exp := fmt.Sprintf("%02d", 10-i)
+		if b, _ := ioutil.ReadFile(a.Path); string(b) != exp {
+			t.Fatalf("Expected: [%s] but actual: [%s] [%s]\n", exp, b, a.Path)
+		}
+	}
+}
+
+// TestRotateWriterInterval is test RotateWriter rotate by time with retention.
+func TestRotateWriterInterval(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	path := filepath.Join(tmp, "app.log")
+	w, err := NewRotateWriter(path, RotateOption{Interval: 24 * time.Hour, Retention: &Retention{Daily: 2}, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("day1\n"))
+	clock.Sleep(time.Hour)
+	w.Write([]byte("day1\n"))
+	if archives, _ := w.Archives(); len(archives) != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 0, len(archives))
+	}
+	for i := 0; i < 4; i++ {
+		clock.Sleep(24 * time.Hour)
+		w.Write([]byte("next\n"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archives, err := w.Archives()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 2 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 2, len(archives))
+	}
+	if !strings.HasPrefix(filepath.Base(archives[1].Path), "app-2024-01-04T") {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", "app-2024-01-04T", archives[1].Path)
+	}
+
+	if _, err := w.Write([]byte("closed")); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
+
+// TestRotateWriterError is test rotation and housekeeping errors never drop written data.
+func TestRotateWriterError(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)}
+	path := filepath.Join(tmp, "app.log")
+	w, err := NewRotateWriter(path, RotateOption{MaxSize: 4, Compress: true, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("1234"))
+
+	// Rename failure keep writing to current file.
+	restore := crossDevice()
+	clock.Sleep(time.Second)
+	if n, err := w.Write([]byte("5678")); n != 4 || err != nil {
+		t.Fatalf("Expected: [%d] [nil] but actual: [%d] [%v]\n", 4, n, err)
+	}
+	restore()
+	if b, _ := ioutil.ReadFile(path); string(b) != "12345678" {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", "12345678", b)
+	}
+	if err := w.Rotate(); err == nil {
+		t.Fatal("Expected: recorded error but actual: [nil]")
+	}
+
+	// Compression failure is returned by Close.
+	w.Write([]byte("abcd"))
+	clock.Sleep(time.Second)
+	os.Mkdir(filepath.Join(tmp, "app-2024-01-01T00-00-02.000.log.gz.tmp"), os.ModePerm)
+	w.Write([]byte("9"))
+	if b, _ := ioutil.ReadFile(path); string(b) != "9" {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", "9", b)
+	}
+	if err := w.Close(); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+
+	if _, err := NewRotateWriter(path, RotateOption{Retention: &Retention{}}); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+}
@@ -0,0 +1,231 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotEntry is entry of SnapshotIndex. Path is slash separated and relative to root.
+type SnapshotEntry struct {
+	Path    string      `json:"p"`
+	Size    int64       `json:"s"`
+	ModTime time.Time   `json:"t"`
+	Mode    os.FileMode `json:"m"`
+	// Dev and Inode are 0 if not supported.
+	Dev   uint64 `json:"d,omitempty"`
+	Inode uint64 `json:"i,omitempty"`
+	// Hash is set if Option.Hash is set.
+	Hash []byte `json:"h,omitempty"`
+}
+
+// SnapshotError is entry failed to read. Path is slash separated and relative to root.
+type SnapshotError struct {
+	Path string `json:"p"`
+	Err  string `json:"e"`
+}
+
+// SnapshotIndex is serializable index of entries under Root.
+type SnapshotIndex struct {
+	Root    string          `json:"root"`
+	Time    time.Time       `json:"time"`
+	Entries []SnapshotEntry `json:"entries"`
+	// Errors is entries failed to read. Entries under them may be missing.
+	Errors []SnapshotError `json:"errors,omitempty"`
+}
+
+// ChangeKind is kind of Change.
+type ChangeKind string
+
+const (
+	// ChangeAdded is entry only in new snapshot.
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is entry only in old snapshot.
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified is entry which content or type changed.
+	ChangeModified ChangeKind = "modified"
+	// ChangeRenamed is entry moved from OldPath, detected by inode or hash.
+	ChangeRenamed ChangeKind = "renamed"
+	// ChangeMetadata is entry which only mode or mtime changed.
+	ChangeMetadata ChangeKind = "metadata"
+)
+
+// Change is difference between two snapshots.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	// OldPath is path in old snapshot if Kind is ChangeRenamed.
+	OldPath string
+	Old     *SnapshotEntry
+	New     *SnapshotEntry
+}
+
+// Snapshot return index of entries under root matched opt. Set opt.Hash to detect content changes and renames by hash.
+// Entries failed to read are recorded in Errors instead of failing whole snapshot.
+func Snapshot(root string, opt Option) (SnapshotIndex, error) {
+	root = filepath.Clean(filepath.FromSlash(root))
+	s := SnapshotIndex{Root: root, Time: time.Now()}
+
+	opt.Archive = false
+	infos, err := GetInfos(root, opt)
+	if err != nil {
+		return s, err
+	}
+	for info := range infos {
+		rel, err := filepath.Rel(root, info.Path)
+		if err != nil {
+			rel = info.Path
+		} else {
+			err = info.Err
+		}
+		if err != nil {
+			// Keep draining, walker is blocked until all infos are received.
+			s.Errors = append(s.Errors, SnapshotError{Path: filepath.ToSlash(rel), Err: err.Error()})
+			continue
+		}
+		if info.Path == root {
+			continue
+		}
+		id, _ := fileID(info.Fi)
+		s.Entries = append(s.Entries, SnapshotEntry{
+			Path:    filepath.ToSlash(rel),
+			Size:    info.Fi.Size(),
+			ModTime: info.Fi.ModTime(),
+			Mode:    info.Fi.Mode(),
+			Dev:     id[0],
+			Inode:   id[1],
+			Hash:    info.Digest,
+		})
+	}
+	sort.Slice(s.Entries, func(i, j int) bool { return s.Entries[i].Path < s.Entries[j].Path })
+	sort.Slice(s.Errors, func(i, j int) bool { return s.Errors[i].Path < s.Errors[j].Path })
+	return s, nil
+}
+
+// WriteSnapshot write s to w as JSON.
+func WriteSnapshot(w io.Writer, s SnapshotIndex) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// ReadSnapshot read snapshot written by WriteSnapshot.
+func ReadSnapshot(r io.Reader) (SnapshotIndex, error) {
+	var s SnapshotIndex
+	err := json.NewDecoder(r).Decode(&s)
+	return s, err
+}
+
+// contentChanged is whether content or type of entry changed.
+// Without hash, mtime change is treated as content change.
+func contentChanged(a, b SnapshotEntry) bool {
+	if a.Mode.Type() != b.Mode.Type() {
+		return true
+	}
+	if a.Mode.IsDir() {
+		return false
+	}
+	if a.Size != b.Size {
+		return true
+	}
+	if a.Hash != nil && b.Hash != nil {
+		return !bytes.Equal(a.Hash, b.Hash)
+	}
+	return !a.ModTime.Equal(b.ModTime)
+}
+
+// metadataChanged is whether mode or mtime changed. Directory mtime is ignored.
+func metadataChanged(a, b SnapshotEntry) bool {
+	if a.Mode != b.Mode {
+		return true
+	}
+	return !a.Mode.IsDir() && !a.ModTime.Equal(b.ModTime)
+}
+
+// underError is whether path is errored path or under it.
+func underError(errs []SnapshotError, path string) bool {
+	for _, e := range errs {
+		if e.Path == "." || path == e.Path || strings.HasPrefix(path, e.Path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff return changes from a to b sorted by path.
+// Entries under Errors of b are not reported as removed, and under Errors of a as added.
+func Diff(a, b SnapshotIndex) []Change {
+	old := make(map[string]*SnapshotEntry, len(a.Entries))
+	for i := range a.Entries {
+		old[a.Entries[i].Path] = &a.Entries[i]
+	}
+	cur := make(map[string]*SnapshotEntry, len(b.Entries))
+	for i := range b.Entries {
+		cur[b.Entries[i].Path] = &b.Entries[i]
+	}
+
+	var (
+		changes []Change
+		added   []*SnapshotEntry
+	)
+	for i := range b.Entries {
+		n := &b.Entries[i]
+		o, ok := old[n.Path]
+		switch {
+		case !ok:
+			added = append(added, n)
+		case contentChanged(*o, *n):
+			changes = append(changes, Change{Kind: ChangeModified, Path: n.Path, Old: o, New: n})
+		case metadataChanged(*o, *n):
+			changes = append(changes, Change{Kind: ChangeMetadata, Path: n.Path, Old: o, New: n})
+		}
+	}
+
+	// Removed entries are candidates of renames.
+	// Inode is unique only within device.
+	byInode := map[[2]uint64]*SnapshotEntry{}
+	byHash := map[string]*SnapshotEntry{}
+	for i := range a.Entries {
+		o := &a.Entries[i]
+		if _, ok := cur[o.Path]; ok {
+			continue
+		}
+		if o.Inode != 0 {
+			byInode[[2]uint64{o.Dev, o.Inode}] = o
+		}
+		if o.Hash != nil && o.Mode.IsRegular() {
+			byHash[string(o.Hash)] = o
+		}
+	}
+	renamed := map[string]bool{}
+	for _, n := range added {
+		o := byInode[[2]uint64{n.Dev, n.Inode}]
+		// Inode may be reused by new file, so content must be same too.
+		if n.Inode == 0 || o == nil || renamed[o.Path] || contentChanged(*o, *n) {
+			o = nil
+			if h := byHash[string(n.Hash)]; n.Hash != nil && n.Mode.IsRegular() && h != nil && !renamed[h.Path] {
+				o = h
+			}
+		}
+		if o == nil {
+			if !underError(a.Errors, n.Path) {
+				changes = append(changes, Change{Kind: ChangeAdded, Path: n.Path, New: n})
+			}
+			continue
+		}
+		renamed[o.Path] = true
+		changes = append(changes, Change{Kind: ChangeRenamed, Path: n.Path, OldPath: o.Path, Old: o, New: n})
+	}
+	for i := range a.Entries {
+		o := &a.Entries[i]
+		if _, ok := cur[o.Path]; !ok && !renamed[o.Path] && !underError(b.Errors, o.Path) {
+			changes = append(changes, Change{Kind: ChangeRemoved, Path: o.Path, Old: o})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
@@ -0,0 +1,142 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSnapshotDiff is test Snapshot and Diff func.
+func TestSnapshotDiff(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	opt := Option{Recurse: true, Hash: sha256.New}
+	ioutil.WriteFile(filepath.Join(tmp, "dir1", "foo"), []byte("foo"), 0644)
+	ioutil.WriteFile(filepath.Join(tmp, "dir1", "bar"), []byte("bar"), 0644)
+	ioutil.WriteFile(filepath.Join(tmp, "dir1", "hoge"), []byte("hoge"), 0644)
+	a, err := Snapshot(tmp, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Serialized snapshot is same.
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	a, err = ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	ioutil.WriteFile(filepath.Join(tmp, "dir1", "foo"), []byte("changed"), 0644)
+	os.Chtimes(filepath.Join(tmp, "dir1", "hoge"), old, old)
+	os.Rename(filepath.Join(tmp, "dir1", "bar"), filepath.Join(tmp, "dir2", "bar"))
+	os.Remove(filepath.Join(tmp, "dir0", "file2"))
+	ioutil.WriteFile(filepath.Join(tmp, "dir2", "new"), []byte("new"), 0644)
+	b, err := Snapshot(tmp, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type change struct {
+		Kind    ChangeKind
+		Path    string
+		OldPath string
+	}
+	var actual []change
+	for _, c := range Diff(a, b) {
+		actual = append(actual, change{c.Kind, c.Path, c.OldPath})
+	}
+	want := []change{
+		{ChangeRemoved, "dir0/file2", ""},
+		{ChangeModified, "dir1/foo", ""},
+		{ChangeMetadata, "dir1/hoge", ""},
+		{ChangeRenamed, "dir2/bar", "dir1/bar"},
+		{ChangeAdded, "dir2/new", ""},
+	}
+	if !reflect.DeepEqual(actual, want) {
+		t.Fatalf("Expected: [%+v] but actual: [%+v]\n", want, actual)
+	}
+
+	if changes := Diff(b, b); len(changes) != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%+v]\n", 0, changes)
+	}
+}
+
+// TestDiffRenameByHash is test Diff detect rename by hash without inode.
+func TestDiffRenameByHash(t *testing.T) {
+	now := time.Now()
+	a := SnapshotIndex{Entries: []SnapshotEntry{{Path: "a", Size: 1, ModTime: now, Hash: []byte{1}}}}
+	b := SnapshotIndex{Entries: []SnapshotEntry{{Path: "b", Size: 1, ModTime: now, Hash: []byte{1}}}}
+	changes := Diff(a, b)
+	if len(changes) != 1 || changes[0].Kind != ChangeRenamed || changes[0].OldPath != "a" {
+		t.Fatalf("Expected: [%s] but actual: [%+v]\n", ChangeRenamed, changes)
+	}
+
+	// Without hash and inode, rename is added and removed.
+	a.Entries[0].Hash, b.Entries[0].Hash = nil, nil
+	if changes := Diff(a, b); len(changes) != 2 {
+		t.Fatalf("Expected: [%d] but actual: [%+v]\n", 2, changes)
+	}
+}
+
+// TestDiffRenameByInode is test Diff match inode on same device only.
+func TestDiffRenameByInode(t *testing.T) {
+	now := time.Now()
+	a := SnapshotIndex{Entries: []SnapshotEntry{{Path: "a", Size: 1, ModTime: now, Dev: 1, Inode: 10}}}
+	b := SnapshotIndex{Entries: []SnapshotEntry{{Path: "b", Size: 1, ModTime: now, Dev: 1, Inode: 10}}}
+	changes := Diff(a, b)
+	if len(changes) != 1 || changes[0].Kind != ChangeRenamed || changes[0].OldPath != "a" {
+		t.Fatalf("Expected: [%s] but actual: [%+v]\n", ChangeRenamed, changes)
+	}
+
+	// Same inode on other device is other file.
+	b.Entries[0].Dev = 2
+	if changes := Diff(a, b); len(changes) != 2 {
+		t.Fatalf("Expected: [%d] but actual: [%+v]\n", 2, changes)
+	}
+}
+
+// TestSnapshotError is test Snapshot func record errors without aborting.
+func TestSnapshotError(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	opt := Option{Recurse: true, Hash: sha256.New}
+	a, err := Snapshot(tmp, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := NewFaultFS(nil,
+		Fault{Match: `dir0$`, Op: FaultReadDir, Err: os.ErrPermission},
+		Fault{Match: `dir1[\\/]foo$`, Op: FaultOpen, Err: os.ErrPermission},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt.FS = fsys
+	b, err := Snapshot(tmp, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Errors) != 2 || b.Errors[0].Path != "dir0" || b.Errors[1].Path != "dir1/foo" {
+		t.Fatalf("Expected: [%s] [%s] but actual: [%+v]\n", "dir0", "dir1/foo", b.Errors)
+	}
+	if len(b.Entries) == 0 {
+		t.Fatal("Expected: other entries are recorded")
+	}
+
+	// Unreadable entries are not removed.
+	if changes := Diff(a, b); len(changes) != 0 {
+		t.Fatalf("Expected: [%d] but actual: [%+v]\n", 0, changes)
+	}
+}
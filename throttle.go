@@ -0,0 +1,94 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Clock is time source of Limiter.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Limiter is token bucket rate limiter. One Limiter can be shared by concurrent copies.
+type Limiter struct {
+	rate  float64
+	burst int64
+	clock Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter return Limiter allowing rate bytes per second with burst bytes.
+// burst 0 is same as rate. clock nil is real time. rate must be positive.
+func NewLimiter(rate, burst int64, clock Clock) (*Limiter, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("Limiter rate: [%d] must be positive", rate)
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &Limiter{
+		rate:   float64(rate),
+		burst:  burst,
+		clock:  clock,
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}, nil
+}
+
+// WaitN block until n bytes are allowed. n should be less than or equal to burst.
+func (l *Limiter) WaitN(n int) {
+	l.mu.Lock()
+	now := l.clock.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+	// Reserve tokens, and wait for deficit outside lock.
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait > 0 {
+		l.clock.Sleep(wait)
+	}
+}
+
+// Reader return io.Reader limited by l. nil Limiter return r.
+func (l *Limiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{r: r, l: l}
+}
+
+type limitedReader struct {
+	r io.Reader
+	l *Limiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > lr.l.burst {
+		p = p[:lr.l.burst]
+	}
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		lr.l.WaitN(n)
+	}
+	return n, err
+}
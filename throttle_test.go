@@ -0,0 +1,112 @@
+package file
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advance time only by Sleep.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// TestLimiter is test Limiter with fake clock.
+func TestLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l, err := NewLimiter(1000, 100, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Burst is free.
+	l.WaitN(100)
+	if d := clock.Now().Sub(time.Unix(0, 0)); d != 0 {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", 0, d)
+	}
+	for i := 0; i < 10; i++ {
+		l.WaitN(100)
+	}
+	if d := clock.Now().Sub(time.Unix(0, 0)); d != time.Second {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", time.Second, d)
+	}
+}
+
+// TestCopyLimiter is test CopyWithOption func with Limiter option.
+func TestCopyLimiter(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	size := 1024 * 1024
+	src := filepath.Join(tmp, "file0")
+	writeRandom(src, size)
+
+	var (
+		rate  int64 = 100 * 1024
+		burst int64 = 64 * 1024
+		start       = time.Unix(0, 0)
+	)
+
+	// Single copy.
+	clock := &fakeClock{now: start}
+	l, err := NewLimiter(rate, burst, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := CopyWithOption(src, filepath.Join(tmp, "dir2", "file0"), CopyOption{Limiter: l})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.N != int64(size) || r.Method != CopyBuffer {
+		t.Fatalf("Expected: [%d] [%v] but actual: [%d] [%v]\n", size, CopyBuffer, r.N, r.Method)
+	}
+	exp := time.Duration(float64(int64(size)-burst) / float64(rate) * float64(time.Second))
+	if d := clock.Now().Sub(start); d < exp-time.Millisecond || d > exp+time.Millisecond {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", exp, d)
+	}
+
+	// Shared by concurrent copies.
+	clock = &fakeClock{now: start}
+	l, err = NewLimiter(rate, burst, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg := new(sync.WaitGroup)
+	for _, name := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if _, err := CopyWithOption(src, filepath.Join(tmp, "dir2", name), CopyOption{Limiter: l}); err != nil {
+				t.Error(err)
+			}
+		}(name)
+	}
+	wg.Wait()
+	exp = time.Duration(float64(3*int64(size)-burst) / float64(rate) * float64(time.Second))
+	if d := clock.Now().Sub(start); d < exp-time.Millisecond {
+		t.Fatalf("Expected: over [%v] but actual: [%v]\n", exp, d)
+	}
+}
+
+// TestNewLimiterRate is test NewLimiter func reject not positive rate.
+func TestNewLimiterRate(t *testing.T) {
+	for _, rate := range []int64{0, -1} {
+		if l, err := NewLimiter(rate, 4096, nil); err == nil || l != nil {
+			t.Fatalf("Expected error but actual: [%v] [%d]\n", err, rate)
+		}
+	}
+}
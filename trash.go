@@ -0,0 +1,22 @@
+package file
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// TrashItem is entry in freedesktop.org trash.
+type TrashItem struct {
+	// Path is original path.
+	Path         string
+	DeletionDate time.Time
+	// Trash is trash directory which has files and info.
+	Trash string
+	// Name is name in trash.
+	Name string
+}
+
+// File return path of trashed entry.
+func (ti TrashItem) File() string {
+	return filepath.Join(ti.Trash, "files", ti.Name)
+}
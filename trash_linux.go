@@ -0,0 +1,316 @@
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const trashTimeFormat = "2006-01-02T15:04:05"
+
+func (ti TrashItem) info() string {
+	return filepath.Join(ti.Trash, "info", ti.Name+".trashinfo")
+}
+
+// trashHome return home trash directory.
+func trashHome() string {
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		data = filepath.Join(os.Getenv("HOME"), ".local", "share")
+	}
+	return filepath.Join(data, "Trash")
+}
+
+func device(path string) (uint64, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(fi.Sys().(*syscall.Stat_t).Dev), nil
+}
+
+// topDir return mount point of path.
+func topDir(path string) (string, error) {
+	dev, err := device(path)
+	if err != nil {
+		return "", err
+	}
+	top := path
+	for {
+		parent := filepath.Dir(top)
+		if parent == top {
+			return top, nil
+		}
+		d, err := device(parent)
+		if err != nil {
+			return "", err
+		}
+		if d != dev {
+			return top, nil
+		}
+		top = parent
+	}
+}
+
+// mountTrash return trash directory of topdir, and create it if needed.
+func mountTrash(top string, create bool) (string, error) {
+	uid := strconv.Itoa(os.Getuid())
+
+	// $topdir/.Trash must be directory with sticky bit and not symlink.
+	shared := filepath.Join(top, ".Trash")
+	if fi, err := os.Lstat(shared); err == nil && fi.IsDir() && fi.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, uid)
+		if !create || os.MkdirAll(dir, 0700) == nil {
+			return dir, nil
+		}
+	}
+
+	dir := filepath.Join(top, ".Trash-"+uid)
+	if create {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// trashTop return topdir of per mount trash directory.
+func trashTop(dir string) string {
+	top := filepath.Dir(dir)
+	if filepath.Base(top) == ".Trash" {
+		top = filepath.Dir(top)
+	}
+	return top
+}
+
+// trashFor return trash directory for path on same device.
+func trashFor(path string) (string, error) {
+	home := trashHome()
+	if err := os.MkdirAll(home, 0700); err != nil {
+		return "", err
+	}
+	pd, err := device(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	hd, err := device(home)
+	if err != nil {
+		return "", err
+	}
+	if pd == hd {
+		return home, nil
+	}
+	top, err := topDir(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return mountTrash(top, true)
+}
+
+// MoveToTrash move path to freedesktop.org trash.
+// Home trash is used for same device, else per mount trash ($topdir/.Trash/$uid or $topdir/.Trash-$uid).
+func MoveToTrash(path string) (TrashItem, error) {
+	var ti TrashItem
+	path, err := filepath.Abs(filepath.FromSlash(path))
+	if err != nil {
+		return ti, err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return ti, err
+	}
+
+	ti.Trash, err = trashFor(path)
+	if err != nil {
+		return ti, err
+	}
+	for _, d := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(ti.Trash, d), 0700); err != nil {
+			return ti, err
+		}
+	}
+
+	// Path is relative to topdir in per mount trash.
+	ti.Path = path
+	infoPath := path
+	if ti.Trash != trashHome() {
+		if rel, err := filepath.Rel(trashTop(ti.Trash), path); err == nil {
+			infoPath = rel
+		}
+	}
+	ti.DeletionDate = time.Now().Truncate(time.Second)
+	content := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", (&url.URL{Path: infoPath}).EscapedPath(), ti.DeletionDate.Format(trashTimeFormat))
+
+	// Reserve unique name by creating info file exclusively.
+	base := filepath.Base(path)
+	for i := 1; ; i++ {
+		ti.Name = base
+		if i > 1 {
+			ti.Name = fmt.Sprintf("%s.%d", base, i)
+		}
+		f, err := os.OpenFile(ti.info(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return ti, err
+		}
+		_, err = f.WriteString(content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(ti.info())
+			return ti, err
+		}
+		break
+	}
+
+	if err := os.Rename(path, ti.File()); err != nil {
+		os.Remove(ti.info())
+		return ti, err
+	}
+	return ti, nil
+}
+
+// trashDirs return home trash and existing per mount trash directories.
+func trashDirs() []string {
+	dirs := []string{trashHome()}
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return dirs
+	}
+	defer f.Close()
+	seen := map[string]bool{dirs[0]: true}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		top := unescapeMount(fields[1])
+		dir, err := mountTrash(top, false)
+		if err != nil || seen[dir] || !IsExistDir(dir) {
+			continue
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// unescapeMount unescape octal escapes (\040) in /proc/self/mounts.
+func unescapeMount(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// ListTrash return items in all trash directories.
+func ListTrash() ([]TrashItem, error) {
+	var items []TrashItem
+	for _, dir := range trashDirs() {
+		fis, err := ioutil.ReadDir(filepath.Join(dir, "info"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return items, err
+		}
+		for _, fi := range fis {
+			if !strings.HasSuffix(fi.Name(), ".trashinfo") {
+				continue
+			}
+			ti, err := readTrashInfo(dir, strings.TrimSuffix(fi.Name(), ".trashinfo"))
+			if err != nil {
+				continue
+			}
+			items = append(items, ti)
+		}
+	}
+	return items, nil
+}
+
+// readTrashInfo parse .trashinfo of name in dir.
+func readTrashInfo(dir, name string) (TrashItem, error) {
+	ti := TrashItem{Trash: dir, Name: name}
+	b, err := ioutil.ReadFile(ti.info())
+	if err != nil {
+		return ti, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Path":
+			ti.Path, err = url.PathUnescape(kv[1])
+			if err != nil {
+				return ti, err
+			}
+		case "DeletionDate":
+			ti.DeletionDate, _ = time.ParseInLocation(trashTimeFormat, kv[1], time.Local)
+		}
+	}
+	if ti.Path == "" {
+		return ti, fmt.Errorf("[%s] has no Path", ti.info())
+	}
+	// Relative path is relative to topdir.
+	if !filepath.IsAbs(ti.Path) {
+		ti.Path = filepath.Join(trashTop(dir), ti.Path)
+	}
+	return ti, nil
+}
+
+// RestoreTrash move item back to original path. Existing original path is not overwritten.
+func RestoreTrash(ti TrashItem) error {
+	if IsExist(ti.Path) {
+		return fmt.Errorf("[%s] already exists", ti.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(ti.Path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(ti.File(), ti.Path); err != nil {
+		return err
+	}
+	return os.Remove(ti.info())
+}
+
+// EmptyTrash remove items permanently. No items means all items in all trash directories.
+func EmptyTrash(items ...TrashItem) error {
+	if len(items) == 0 {
+		var err error
+		items, err = ListTrash()
+		if err != nil {
+			return err
+		}
+	}
+	for _, ti := range items {
+		if err := os.RemoveAll(ti.File()); err != nil {
+			return err
+		}
+		if err := os.Remove(ti.info()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
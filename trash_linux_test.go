@@ -0,0 +1,101 @@
+//go:build linux
+// +build linux
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTrash is test MoveToTrash, ListTrash, RestoreTrash and EmptyTrash func.
+func TestTrash(t *testing.T) {
+	tmp := setup()
+	defer shutdown(tmp)
+
+	old := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", old)
+	os.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+
+	src := filepath.Join(tmp, "dir0", "file 0")
+	ioutil.WriteFile(src, []byte("test"), 0644)
+	ti, err := MoveToTrash(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsExist(src) || !IsExist(ti.File()) {
+		t.Fatalf("Expected: moved to [%s] but actual: not moved\n", ti.File())
+	}
+	b, err := ioutil.ReadFile(filepath.Join(ti.Trash, "info", ti.Name+".trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "[Trash Info]\n") || !strings.Contains(string(b), "file%200") {
+		t.Fatalf("Expected: escaped trashinfo but actual: [%s]\n", b)
+	}
+
+	// Same name get unique name in trash.
+	ioutil.WriteFile(src, []byte("test"), 0644)
+	ti2, err := MoveToTrash(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti2.Name != "file 0.2" {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", "file 0.2", ti2.Name)
+	}
+	if _, err := MoveToTrash(src); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+
+	items, err := listTestTrash(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 2, len(items))
+	}
+	for _, it := range items {
+		if it.Path != src || !it.DeletionDate.Equal(ti.DeletionDate) && !it.DeletionDate.Equal(ti2.DeletionDate) {
+			t.Fatalf("Expected: [%s] but actual: [%+v]\n", src, it)
+		}
+	}
+
+	if err := RestoreTrash(ti); err != nil {
+		t.Fatal(err)
+	}
+	if !IsExist(src) || IsExist(ti.File()) {
+		t.Fatalf("Expected: restored [%s] but actual: not restored\n", src)
+	}
+	if err := RestoreTrash(ti2); err == nil {
+		t.Fatal("Expected error but actual: [nil]")
+	}
+
+	if err := EmptyTrash(ti2); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := listTestTrash(tmp); len(items) != 0 || IsExist(ti2.File()) {
+		t.Fatalf("Expected: [%d] but actual: [%d]\n", 0, len(items))
+	}
+}
+
+// listTestTrash return trash items under tmp, ignoring trash of other mounts.
+func listTestTrash(tmp string) ([]TrashItem, error) {
+	all, err := ListTrash()
+	var items []TrashItem
+	for _, it := range all {
+		if strings.HasPrefix(it.Trash, tmp) {
+			items = append(items, it)
+		}
+	}
+	return items, err
+}
+
+// TestUnescapeMount is test unescapeMount func.
+func TestUnescapeMount(t *testing.T) {
+	if s := unescapeMount(`/mnt/my\040disk`); s != "/mnt/my disk" {
+		t.Fatalf("Expected: [%s] but actual: [%s]\n", "/mnt/my disk", s)
+	}
+}
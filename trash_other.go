@@ -0,0 +1,31 @@
+//go:build !linux
+// +build !linux
+
+package file
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var errTrash = fmt.Errorf("trash is not supported on %s", runtime.GOOS)
+
+// MoveToTrash is not supported on this platform.
+func MoveToTrash(path string) (TrashItem, error) {
+	return TrashItem{}, errTrash
+}
+
+// ListTrash is not supported on this platform.
+func ListTrash() ([]TrashItem, error) {
+	return nil, errTrash
+}
+
+// RestoreTrash is not supported on this platform.
+func RestoreTrash(ti TrashItem) error {
+	return errTrash
+}
+
+// EmptyTrash is not supported on this platform.
+func EmptyTrash(items ...TrashItem) error {
+	return errTrash
+}